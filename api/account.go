@@ -0,0 +1,291 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// password hashing, PBKDF2 with HMAC-SHA256
+const (
+	passwordSaltSize   = 16
+	passwordHashSize   = 32
+	MinPasswordLength  = 8
+	passwordIterations = 100000
+)
+
+// predefined errors
+
+var (
+	InvalidUsernameErr    = errors.New("invalid username. constraints: 3 to 20 letters, digits, '_' or '-'")
+	UsernameTakenErr      = errors.New("username already taken. please choose another one")
+	WeakPasswordErr       = fmt.Errorf("password too short. constraints: at least %d characters", MinPasswordLength)
+	InvalidCredentialsErr = errors.New("invalid username or password")
+	AccountNotFoundErr    = errors.New("account not found")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+
+// Account a registered player, games played while signed in record the account id of the player
+type Account struct {
+	Id        string
+	Username  string
+	CreatedAt time.Time
+	// salt random salt the password was hashed with
+	salt []byte
+	// passwordHash PBKDF2 of the password, the plain password is never stored
+	passwordHash []byte
+	iterations   int
+}
+
+// AccountStore accounts by id, kept in memory like sessions. accounts outlive the sessions signed in to them
+type AccountStore struct {
+	mu   *sync.RWMutex
+	byId map[string]*Account
+	// byUsername accounts by lower case username, usernames are unique regardless of case
+	byUsername map[string]*Account
+}
+
+// NewAccountStore returns an empty store
+func NewAccountStore() *AccountStore {
+	return &AccountStore{
+		mu:         &sync.RWMutex{},
+		byId:       map[string]*Account{},
+		byUsername: map[string]*Account{},
+	}
+}
+
+// Get returns the account with the id
+func (a *AccountStore) Get(accountId string) (*Account, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	account, found := a.byId[accountId]
+	if !found {
+		return nil, AccountNotFoundErr
+	}
+	return account, nil
+}
+
+// Register creates an account with a unique username and returns it
+func (a *AccountStore) Register(username, password string) (*Account, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, InvalidUsernameErr
+	}
+	if len(password) < MinPasswordLength {
+		return nil, WeakPasswordErr
+	}
+	salt := make([]byte, passwordSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	account := &Account{
+		Id:           uuid.NewString(),
+		Username:     username,
+		CreatedAt:    time.Now(),
+		salt:         salt,
+		passwordHash: hashPassword(password, salt, passwordIterations),
+		iterations:   passwordIterations,
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := strings.ToLower(username)
+	if _, taken := a.byUsername[key]; taken {
+		return nil, UsernameTakenErr
+	}
+	a.byId[account.Id] = account
+	a.byUsername[key] = account
+	return account, nil
+}
+
+// Authenticate returns the account if the password matches, unknown usernames and wrong passwords fail alike
+func (a *AccountStore) Authenticate(username, password string) (*Account, error) {
+	a.mu.RLock()
+	account, found := a.byUsername[strings.ToLower(username)]
+	a.mu.RUnlock()
+	if !found {
+		// hash anyway so unknown usernames take as long as wrong passwords
+		hashPassword(password, make([]byte, passwordSaltSize), passwordIterations)
+		return nil, InvalidCredentialsErr
+	}
+	if !hmac.Equal(hashPassword(password, account.salt, account.iterations), account.passwordHash) {
+		return nil, InvalidCredentialsErr
+	}
+	return account, nil
+}
+
+// hashPassword derives the password hash with PBKDF2 (RFC 8018) using HMAC-SHA256, the output is a single block
+func hashPassword(password string, salt []byte, iterations int) []byte {
+	return pbkdf2([]byte(password), salt, iterations, passwordHashSize, sha256.New)
+}
+
+// pbkdf2 derives a key of keyLen bytes from the password and salt as in RFC 8018 section 5.2, kept in the standard
+// library only instead of depending on golang.org/x/crypto. checked against the test vectors of RFC 7914 section 11
+func pbkdf2(password, salt []byte, iterations, keyLen int, h func() hash.Hash) []byte {
+	prf := hmac.New(h, password)
+	size := prf.Size()
+	blocks := (keyLen + size - 1) / size
+	key := make([]byte, 0, blocks*size)
+	var counter [4]byte
+	u := make([]byte, size)
+	for block := 1; block <= blocks; block++ {
+		// U1 = PRF(password, salt || INT(block)), T = U1 xor U2 xor ... xor Uc
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		u = prf.Sum(u[:0])
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// Register creates an account, players sign in to it with Login
+func (s *Server) Register(username, password string) (*Account, error) {
+	return s.Accounts.Register(username, password)
+}
+
+// Login checks the credentials and creates a new session signed in to the account, returns the session id and
+// the account
+func (s *Server) Login(username, password string) (string, *Account, error) {
+	account, err := s.Accounts.Authenticate(username, password)
+	if err != nil {
+		return "", nil, err
+	}
+	sessionId, err := s.newSession(account)
+	if err != nil {
+		return "", nil, err
+	}
+	return sessionId, account, nil
+}
+
+// register create an account
+func (s *Server) register() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body CredentialsReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, fmt.Errorf("invalid request body, %w", err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		account, err := s.Register(body.Username, body.Password)
+		if errors.Is(err, InvalidUsernameErr) || errors.Is(err, WeakPasswordErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if errors.Is(err, UsernameTakenErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &AccountResp{
+			AccountId: account.Id,
+			Username:  account.Username,
+			CreatedAt: account.CreatedAt,
+			Rating:    s.Ratings.Get(account.Id),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// login sign in to an account, the response holds the token of a new session
+func (s *Server) login() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body CredentialsReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, fmt.Errorf("invalid request body, %w", err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		sessionId, account, err := s.Login(body.Username, body.Password)
+		if errors.Is(err, InvalidCredentialsErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		token, expiresAt, err := s.Tokens.Sign(sessionId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &LoginResp{
+			CreateNewSessionResp: CreateNewSessionResp{
+				SessionId: sessionId,
+				Token:     token,
+				ExpiresAt: expiresAt,
+			},
+			AccountId: account.Id,
+			Username:  account.Username,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// getAccount public profile of an account
+func (s *Server) getAccount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId, found := mux.Vars(r)["accountId"]
+		if !found {
+			http.Error(w, errors.New("account id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+		account, err := s.Accounts.Get(accountId)
+		if errors.Is(err, AccountNotFoundErr) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &AccountResp{
+			AccountId: account.Id,
+			Username:  account.Username,
+			CreatedAt: account.CreatedAt,
+			Rating:    s.Ratings.Get(account.Id),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestPbkdf2(t *testing.T) {
+	// PBKDF2-HMAC-SHA256 known answers, the 64 byte keys are the PBKDF2 test vectors of RFC 7914 section 11
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		keyLen     int
+		want       string
+	}{
+		{password: "password", salt: "salt", iterations: 1, keyLen: 32, want: "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{password: "password", salt: "salt", iterations: 2, keyLen: 32, want: "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{password: "password", salt: "salt", iterations: 4096, keyLen: 32, want: "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{password: "passwd", salt: "salt", iterations: 1, keyLen: 64, want: "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{password: "Password", salt: "NaCl", iterations: 80000, keyLen: 64, want: "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %d", tt.password, tt.iterations), func(t *testing.T) {
+			got := hex.EncodeToString(pbkdf2([]byte(tt.password), []byte(tt.salt), tt.iterations, tt.keyLen, sha256.New))
+			if got != tt.want {
+				t.Errorf("pbkdf2() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccountStore_Register(t *testing.T) {
+	store := NewAccountStore()
+	if _, err := store.Register("alice", "correct horse"); err != nil {
+		t.Fatalf("unexpect error %s", err)
+	}
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  error
+	}{
+		{name: "taken in another case", username: "Alice", password: "correct horse", wantErr: UsernameTakenErr},
+		{name: "too short username", username: "al", password: "correct horse", wantErr: InvalidUsernameErr},
+		{name: "spaces in username", username: "alice smith", password: "correct horse", wantErr: InvalidUsernameErr},
+		{name: "too short password", username: "bob", password: "secret", wantErr: WeakPasswordErr},
+		{name: "valid", username: "bob_2", password: "correct horse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.Register(tt.username, tt.password); err != tt.wantErr {
+				t.Errorf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := NewServer()
+	var alice, bob AccountResp
+	decode(t, serve(t, s, http.MethodPost, "/accounts", "", CredentialsReq{Username: "alice", Password: "correct horse"}), &alice)
+	decode(t, serve(t, s, http.MethodPost, "/accounts", "", CredentialsReq{Username: "bob", Password: "battery staple"}), &bob)
+	if w := serve(t, s, http.MethodPost, "/accounts", "", CredentialsReq{Username: "alice", Password: "another one"}); w.Code != http.StatusConflict {
+		t.Errorf("registering a taken username returns %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := serve(t, s, http.MethodPost, "/login", "", CredentialsReq{Username: "alice", Password: "battery staple"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("login with a wrong password returns %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	var aliceLogin, bobLogin LoginResp
+	decode(t, serve(t, s, http.MethodPost, "/login", "", CredentialsReq{Username: "ALICE", Password: "correct horse"}), &aliceLogin)
+	decode(t, serve(t, s, http.MethodPost, "/login", "", CredentialsReq{Username: "bob", Password: "battery staple"}), &bobLogin)
+	if aliceLogin.AccountId != alice.AccountId || aliceLogin.Username != "alice" {
+		t.Fatalf("expect to sign in to alice's account, got %+v", aliceLogin)
+	}
+	var current GetCurrentSessionResp
+	decode(t, serve(t, s, http.MethodGet, "/session", aliceLogin.Token, nil), &current)
+	if current.AccountId != alice.AccountId {
+		t.Errorf("expect the session to be signed in to %s, got %q", alice.AccountId, current.AccountId)
+	}
+
+	// games record the accounts, signed in players play under their username
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", aliceLogin.Token, CreateNewGameReq{}), &created)
+	var second LoginResp
+	decode(t, serve(t, s, http.MethodPost, "/login", "", CredentialsReq{Username: "alice", Password: "correct horse"}), &second)
+	if w := serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), second.Token, JoinGameReq{PlayerName: "alice again"}); w.Code != http.StatusConflict {
+		t.Errorf("joining a game of the same account returns %d, want %d", w.Code, http.StatusConflict)
+	}
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), bobLogin.Token, JoinGameReq{}), &JoinGameResp{})
+	var state GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, "/games/"+created.GameId, aliceLogin.Token, nil), &state)
+	if len(state.Players) != 2 || state.Players[0].Name != "alice" || state.Players[0].AccountId != alice.AccountId ||
+		state.Players[1].Name != "bob" || state.Players[1].AccountId != bob.AccountId {
+		t.Errorf("expect alice and bob to play with their accounts, got %+v", state.Players)
+	}
+
+	var profile AccountResp
+	decode(t, serve(t, s, http.MethodGet, "/accounts/"+bob.AccountId, "", nil), &profile)
+	if profile.Username != "bob" {
+		t.Errorf("expect bob's profile, got %+v", profile)
+	}
+}
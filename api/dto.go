@@ -1,31 +1,161 @@
 package api
 
+import (
+	"time"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
 // request body and response body for APIs
 
 type CreateNewSessionResp struct {
 	SessionId string `json:"sessionId"`
+	// Token bearer token authenticating the session, sent as Authorization: Bearer <token>
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type RefreshSessionResp struct {
+	SessionId string    `json:"sessionId"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type StreamTokenResp struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
 type GetCurrentSessionResp struct {
 	SessionId string `json:"sessionId"`
 	GameId    string `json:"gameId"`
+	// AccountId account the session is signed in to, empty for guests
+	AccountId string `json:"accountId,omitempty"`
+}
+
+type CredentialsReq struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type AccountResp struct {
+	AccountId string    `json:"accountId"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+	Rating    Rating    `json:"rating"`
+}
+
+type RatingHistoryResp struct {
+	Rating Rating `json:"rating"`
+	// History rating changes after rated games, newest first
+	History []RatingChange `json:"history"`
+}
+
+type LoginResp struct {
+	CreateNewSessionResp
+	AccountId string `json:"accountId"`
+	Username  string `json:"username"`
 }
 
 type CreateNewGameReq struct {
 	PlayerName string `json:"playerName"`
+	// Variant optional name of the game variant, defaults to classic. see GET /variants
+	Variant string `json:"variant,omitempty"`
+	// BoardSize optional size n of the n x n board, defaults to 3
+	BoardSize int `json:"boardSize,omitempty"`
+	// WinLength optional number of marks in a row needed to win, defaults to the board size
+	WinLength int `json:"winLength,omitempty"`
+	// ExactWinLength optional, lines longer than win length (overlines) do not win
+	ExactWinLength bool `json:"exactWinLength,omitempty"`
+	// Opening optional opening protocol, "swap2" for the gomoku variant
+	Opening string `json:"opening,omitempty"`
+	// Bot optional level of a server side opponent, "random", "heuristic" or "minimax". the game starts right away
+	Bot string `json:"bot,omitempty"`
+	// TimeControl optional clocks, the player to move loses once the clock runs out
+	TimeControl *TimeControlReq `json:"timeControl,omitempty"`
+	// Private optional, the game is hidden from spectators and from the open games list and is joined with the
+	// invite code of the response
+	Private bool `json:"private,omitempty"`
+}
+
+// TimeControlReq either seconds per move, or initial seconds with an optional increment in seconds per move
+type TimeControlReq struct {
+	PerMoveSeconds   int `json:"perMoveSeconds,omitempty"`
+	InitialSeconds   int `json:"initialSeconds,omitempty"`
+	IncrementSeconds int `json:"incrementSeconds,omitempty"`
 }
 
 type CreateNewGameResp struct {
 	GameId   string `json:"gameId"`
 	PlayerId string `json:"playerId"`
+	// InviteCode code friends join the private game with, see POST /invites/{code}/join
+	InviteCode      string     `json:"inviteCode,omitempty"`
+	InviteExpiresAt *time.Time `json:"inviteExpiresAt,omitempty"`
+}
+
+type CreateInviteResp struct {
+	InviteCode string    `json:"inviteCode"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+type JoinGameByCodeResp struct {
+	GameId   string `json:"gameId"`
+	PlayerId string `json:"playerId"`
 }
 
 type ListOpenGamesResp struct {
-	SessionIdAndGameIds map[string]string `json:"sessionIdAndGameIds"`
+	// Games never null, empty once no game passes the filter
+	Games []OpenGame `json:"games"`
+	// NextCursor pass as the cursor query parameter for the next page, empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type ListVariantsResp struct {
+	Variants []string `json:"variants"`
+}
+
+type GetMetricsResp struct {
+	ActiveSessions int `json:"activeSessions"`
+	// SessionsReaped idle sessions removed since the server started
+	SessionsReaped int64 `json:"sessionsReaped"`
+	// GamesReaped active games ended because their session was reaped
+	GamesReaped int64 `json:"gamesReaped"`
 }
 
+// GetGameStateResp structured game state, the text rendering is returned as text/plain with
+// the Accept: text/plain header or the format=text query parameter
 type GetGameStateResp struct {
-	State string `json:"state"`
+	game.GameView
+}
+
+type AnalyzeGameResp struct {
+	GameId string `json:"gameId"`
+	End    bool   `json:"end"`
+	// PlayerToMove name of the player the outcomes are given for, empty for finished games
+	PlayerToMove string `json:"playerToMove"`
+	// Moves outcome and distance to the result under perfect play for every empty position
+	Moves []game.MoveAnalysis `json:"moves"`
+}
+
+type GetMovesResp struct {
+	GameId string            `json:"gameId"`
+	Moves  []game.MoveRecord `json:"moves"`
+}
+
+type ReplayGameResp struct {
+	GameId string `json:"gameId"`
+	// Ply number of moves replayed
+	Ply int `json:"ply"`
+	// Board 1 for X, -1 for O and 0 for an empty position
+	Board     [][]int         `json:"board"`
+	MetaBoard *game.MetaBoard `json:"metaBoard,omitempty"`
+	State     string          `json:"state"`
+}
+
+// WaitForChangeResp game state after a long poll, changed is false if the wait timed out
+type WaitForChangeResp struct {
+	Changed bool `json:"changed"`
+	game.GameView
 }
 
 type JoinGameReq struct {
@@ -42,10 +172,84 @@ type EndGameReq struct {
 
 type PlayMoveReq struct {
 	PlayerId string `json:"playerId"`
-	Row      int    `json:"row"`
-	Column   int    `json:"column"`
+	// SubBoard optional sub-board to play in for the ultimate variant, row and column are then positions inside the sub-board
+	SubBoard *game.Position `json:"subBoard,omitempty"`
+	Row      int            `json:"row"`
+	Column   int            `json:"column"`
 }
 
 type PlayMoveResp struct {
-	State string `json:"state"`
+	game.GameView
+}
+
+type ChooseColorReq struct {
+	PlayerId string `json:"playerId"`
+	// Choice "X", "O" or "place-two"
+	Choice string `json:"choice"`
+}
+
+type ChooseColorResp struct {
+	game.GameView
+}
+
+type TakebackReq struct {
+	PlayerId string `json:"playerId"`
+}
+
+type TakebackResp struct {
+	game.GameView
+}
+
+type ResignReq struct {
+	PlayerId string `json:"playerId"`
+}
+
+type ResignResp struct {
+	game.GameView
+}
+
+type DrawReq struct {
+	PlayerId string `json:"playerId"`
+}
+
+type DrawResp struct {
+	game.GameView
+}
+
+type RematchReq struct {
+	PlayerId string `json:"playerId"`
+}
+
+type RematchResp struct {
+	game.GameView
+}
+
+type EnterQueueReq struct {
+	PlayerName string `json:"playerName"`
+	// Variant variant to play, empty for any
+	Variant string `json:"variant,omitempty"`
+	// RatingRange accepted rating difference to the opponent at first, widened while waiting.
+	// defaults to DefaultRatingRange
+	RatingRange float64 `json:"ratingRange,omitempty"`
+}
+
+type MatchmakingResp struct {
+	QueueTicket
+}
+
+// WsPlayMoveReq move sent over the game websocket
+type WsPlayMoveReq struct {
+	// Type "move"
+	Type     string         `json:"type"`
+	PlayerId string         `json:"playerId"`
+	SubBoard *game.Position `json:"subBoard,omitempty"`
+	Row      int            `json:"row"`
+	Column   int            `json:"column"`
+}
+
+// WsErrorResp answer to a websocket message that could not be handled, the connection stays open
+type WsErrorResp struct {
+	// Type "error"
+	Type  string `json:"type"`
+	Error string `json:"error"`
 }
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
+// subscriberBuffer events a subscriber can fall behind before it is dropped
+const subscriberBuffer = 16
+
+// DefaultEventLogSize number of latest events kept for clients resuming a stream
+const DefaultEventLogSize = 1000
+
+// EventType what happened to a game
+type EventType string
+
+const (
+	// EventOpen a game waiting for a second player was created
+	EventOpen EventType = "open"
+	// EventJoin the second player joined, the game is filled
+	EventJoin     EventType = "join"
+	EventMove     EventType = "move"
+	EventColor    EventType = "color"
+	EventTakeback EventType = "takeback"
+	// EventDraw a draw was offered or declined, an agreed draw ends the game with EventEnd
+	EventDraw EventType = "draw"
+	// EventEnd the game ended on the board, by resignation, by agreement or because a player abandoned it,
+	// see the reason in the state
+	EventEnd EventType = "end"
+	// EventSpectators a spectator started or stopped watching the game
+	EventSpectators EventType = "spectators"
+	// EventRematch a rematch of the finished game was requested or created
+	EventRematch EventType = "rematch"
+	// EventTimeout the game ended because the player to move ran out of time
+	EventTimeout EventType = "timeout"
+)
+
+// Event a state change of a game with the state after the change
+type Event struct {
+	// Id increasing sequence number of the event, 0 for the snapshot sent when a websocket connects
+	Id     int64         `json:"id"`
+	Type   EventType     `json:"type"`
+	GameId string        `json:"gameId"`
+	Time   time.Time     `json:"time"`
+	State  game.GameView `json:"state"`
+}
+
+// EventBus numbers game events and fans them out to subscribers, publishing never blocks on slow subscribers, they
+// are dropped instead. the latest events are kept in a bounded log so clients can catch up on what they missed
+type EventBus struct {
+	mu          *sync.Mutex
+	subscribers map[chan Event]string
+	// log latest events in publishing order, at most logSize
+	log     []Event
+	logSize int
+	lastId  int64
+}
+
+// NewEventBus returns a bus keeping the latest logSize events
+func NewEventBus(logSize int) *EventBus {
+	return &EventBus{
+		mu:          &sync.Mutex{},
+		subscribers: map[chan Event]string{},
+		logSize:     logSize,
+	}
+}
+
+// Subscribe returns a channel receiving the events of the game, of all games if game id is empty,
+// and a function to cancel the subscription which closes the channel. the channel is also closed if the subscriber
+// falls behind, it resumes from the event log with Since
+func (b *EventBus) Subscribe(gameId string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	b.subscribers[ch] = gameId
+	b.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			// dropped subscribers are closed already
+			if _, found := b.subscribers[ch]; found {
+				delete(b.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+}
+
+// Publish numbers the event, adds it to the log and sends it to the subscribers of its game,
+// subscribers with a full buffer are dropped and their channel is closed
+func (b *EventBus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastId++
+	e.Id = b.lastId
+	if b.logSize > 0 {
+		if len(b.log) == b.logSize {
+			b.log = append(b.log[:0], b.log[1:]...)
+		}
+		b.log = append(b.log, e)
+	}
+	for ch, gameId := range b.subscribers {
+		if gameId != "" && gameId != e.GameId {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// LastId returns the id of the latest event, 0 before the first event
+func (b *EventBus) LastId() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastId
+}
+
+// Since returns the logged events after the event id of the game, of all games if game id is empty.
+// events older than the log are lost
+func (b *EventBus) Since(lastId int64, gameId string) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var events []Event
+	for _, e := range b.log {
+		if e.Id > lastId && (gameId == "" || gameId == e.GameId) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// publish publishes the current state of the game
+func (s *Server) publish(eventType EventType, g *game.Game) {
+	s.Events.Publish(Event{
+		Type:   eventType,
+		GameId: g.Id,
+		Time:   time.Now(),
+		State:  g.View(),
+	})
+}
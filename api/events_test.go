@@ -0,0 +1,91 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEventBus_Publish(t *testing.T) {
+	b := NewEventBus(DefaultEventLogSize)
+	game1, cancel1 := b.Subscribe("game1")
+	all, cancelAll := b.Subscribe("")
+	defer cancelAll()
+
+	b.Publish(Event{Type: EventMove, GameId: "game1"})
+	b.Publish(Event{Type: EventMove, GameId: "game2"})
+	if e := <-game1; e.GameId != "game1" {
+		t.Errorf("game subscriber got event of %s", e.GameId)
+	}
+	if len(game1) != 0 {
+		t.Errorf("game subscriber got %d events of other games", len(game1))
+	}
+	if len(all) != 2 {
+		t.Errorf("subscriber of all games got %d events, want 2", len(all))
+	}
+
+	// cancelled subscriptions are closed and no longer receive events
+	cancel1()
+	cancel1()
+	if _, ok := <-game1; ok {
+		t.Error("expect the cancelled subscription to be closed")
+	}
+	b.Publish(Event{Type: EventEnd, GameId: "game1"})
+
+	// a slow subscriber is dropped instead of blocking the publisher, it keeps the events it got
+	for i := 0; i < subscriberBuffer*2; i++ {
+		b.Publish(Event{Type: EventMove, GameId: "game1"})
+	}
+	var received int
+	for range all {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("slow subscriber got %d events before it was dropped, want %d", received, subscriberBuffer)
+	}
+	if b.LastId() != int64(subscriberBuffer*2+3) {
+		t.Errorf("LastId() = %d, want %d", b.LastId(), subscriberBuffer*2+3)
+	}
+}
+
+func TestEventBus_Since(t *testing.T) {
+	b := NewEventBus(3)
+	for _, gameId := range []string{"game1", "game2", "game1", "game2", "game1"} {
+		b.Publish(Event{Type: EventMove, GameId: gameId})
+	}
+	tests := []struct {
+		name    string
+		lastId  int64
+		gameId  string
+		wantIds []int64
+	}{
+		{
+			name:    "older events are dropped from the log",
+			wantIds: []int64{3, 4, 5},
+		},
+		{
+			name:    "events after the last id",
+			lastId:  3,
+			wantIds: []int64{4, 5},
+		},
+		{
+			name:    "events of one game",
+			gameId:  "game1",
+			wantIds: []int64{3, 5},
+		},
+		{
+			name:   "up to date",
+			lastId: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			for _, e := range b.Since(tt.lastId, tt.gameId) {
+				got = append(got, e.Id)
+			}
+			if !reflect.DeepEqual(got, tt.wantIds) {
+				t.Errorf("Since() ids %v, want %v", got, tt.wantIds)
+			}
+		})
+	}
+}
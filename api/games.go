@@ -0,0 +1,108 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
+// ActiveGameIndex active games by game id and sessions by the player id they play as, so games and opponents are
+// found without scanning every session
+type ActiveGameIndex struct {
+	mu    *sync.RWMutex
+	games map[string]*activeGame
+	// sessions session by its latest player id, kept once the game finished so the opponent can ask for a rematch
+	sessions  map[string]*Session
+	playerIds map[*Session]string
+}
+
+// activeGame an active game and the sessions playing it
+type activeGame struct {
+	game   *game.Game
+	seated map[*Session]bool
+}
+
+// NewActiveGameIndex returns an empty index
+func NewActiveGameIndex() *ActiveGameIndex {
+	return &ActiveGameIndex{
+		mu:        &sync.RWMutex{},
+		games:     map[string]*activeGame{},
+		sessions:  map[string]*Session{},
+		playerIds: map[*Session]string{},
+	}
+}
+
+// Enter records that the session plays the game as the player
+func (i *ActiveGameIndex) Enter(session *Session, g *game.Game, playerId string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	a, found := i.games[g.Id]
+	if !found {
+		a = &activeGame{game: g, seated: map[*Session]bool{}}
+		i.games[g.Id] = a
+	}
+	a.seated[session] = true
+	if previous, found := i.playerIds[session]; found {
+		delete(i.sessions, previous)
+	}
+	i.sessions[playerId] = session
+	i.playerIds[session] = playerId
+}
+
+// Leave records that the session no longer plays the game, the game is dropped once no session plays it
+func (i *ActiveGameIndex) Leave(session *Session, g *game.Game) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	a, found := i.games[g.Id]
+	if !found || a.game != g {
+		return
+	}
+	delete(a.seated, session)
+	if len(a.seated) == 0 {
+		delete(i.games, g.Id)
+	}
+}
+
+// Remove drops the finished game and returns the sessions playing it
+func (i *ActiveGameIndex) Remove(g *game.Game) []*Session {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	a, found := i.games[g.Id]
+	if !found || a.game != g {
+		return nil
+	}
+	delete(i.games, g.Id)
+	sessions := make([]*Session, 0, len(a.seated))
+	for session := range a.seated {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// Forget drops the player id of the ended session
+func (i *ActiveGameIndex) Forget(session *Session) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if playerId, found := i.playerIds[session]; found {
+		delete(i.sessions, playerId)
+		delete(i.playerIds, session)
+	}
+}
+
+// Get returns the active game with the game id
+func (i *ActiveGameIndex) Get(gameId string) (*game.Game, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	a, found := i.games[gameId]
+	if !found {
+		return nil, false
+	}
+	return a.game, true
+}
+
+// Session returns the session playing as the player id, nil if there is none
+func (i *ActiveGameIndex) Session(playerId string) *Session {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.sessions[playerId]
+}
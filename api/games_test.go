@@ -0,0 +1,47 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
+func TestActiveGameIndex(t *testing.T) {
+	i := NewActiveGameIndex()
+	g := &game.Game{Id: "game1"}
+	host, joiner := &Session{Id: "host"}, &Session{Id: "joiner"}
+
+	i.Enter(host, g, "player1")
+	i.Enter(joiner, g, "player2")
+	if got, found := i.Get("game1"); !found || got != g {
+		t.Fatalf("Get() = %v, %v, want the game", got, found)
+	}
+	if got := i.Session("player2"); got != joiner {
+		t.Errorf("Session() = %v, want the joiner", got)
+	}
+
+	// the game stays active while a session plays it
+	i.Leave(host, g)
+	if _, found := i.Get("game1"); !found {
+		t.Error("expect the game to stay active for the joiner")
+	}
+	if sessions := i.Remove(g); len(sessions) != 1 || sessions[0] != joiner {
+		t.Errorf("Remove() = %v, want the joiner", sessions)
+	}
+	if _, found := i.Get("game1"); found {
+		t.Error("expect the finished game to be removed")
+	}
+
+	// players are found after their game finished until they play as someone else or their session ends
+	if got := i.Session("player1"); got != host {
+		t.Errorf("Session() = %v, want the host", got)
+	}
+	i.Enter(host, &game.Game{Id: "game2"}, "player3")
+	if got := i.Session("player1"); got != nil {
+		t.Errorf("Session() = %v, want nil for an old player id", got)
+	}
+	i.Forget(joiner)
+	if got := i.Session("player2"); got != nil {
+		t.Errorf("Session() = %v, want nil for an ended session", got)
+	}
+}
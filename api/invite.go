@@ -0,0 +1,226 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
+// DefaultInviteTTL how long the invite code of a private game can be used to join it
+const DefaultInviteTTL = 15 * time.Minute
+
+// invite codes are short and avoid characters read alike, such as 0 and O or 1 and I. 32^8 codes keep guessing
+// hopeless together with the attempt limit
+const (
+	inviteCodeLength   = 8
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// sessions trying unknown invite codes are refused for the rest of the window once they reach the attempt limit
+const (
+	maxInviteAttempts   = 5
+	inviteAttemptWindow = time.Minute
+)
+
+// predefined errors
+
+var (
+	InviteCodeNotFoundErr = errors.New("invite code not found or expired. ask the host for a new one")
+	NotPrivateGameErr     = errors.New("invite codes are only for private games. public games are joined by their id")
+	InviteAttemptsErr     = fmt.Errorf("too many unknown invite codes. try again in %v", inviteAttemptWindow)
+)
+
+// WithInviteTTL changes how long invite codes of private games are valid, defaults to DefaultInviteTTL
+func WithInviteTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.InviteTTL = ttl
+	}
+}
+
+// CreateInvite issues an invite code for the private game of the session waiting for a second player and returns it
+// with its expiry. codes issued before stay valid until they expire
+func (s *Server) CreateInvite(sessionId, gameId string) (string, time.Time, error) {
+	session, err := s.authenticateSessionId(sessionId)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	g, err := session.GetGameState(gameId)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if !g.Private {
+		return "", time.Time{}, NotPrivateGameErr
+	}
+	if g.Player2Id != "" {
+		return "", time.Time{}, game.GameFilledWithMaxPlayerErr
+	}
+	for {
+		code, err := newInviteCode()
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		// codes are drawn again in the unlikely case they are taken
+		if err := s.Invites.Add(code, gameId, s.InviteTTL); err == nil {
+			return code, time.Now().Add(s.InviteTTL), nil
+		}
+	}
+}
+
+// JoinGameByCode joins the game the invite code was issued for, the code can not be used again.
+// returns the game id and a player2 id
+func (s *Server) JoinGameByCode(sessionId, code, playerName string) (string, string, error) {
+	session, err := s.authenticateSessionId(sessionId)
+	if err != nil {
+		return "", "", err
+	}
+	if attempts, found := s.InviteAttempts.Get(sessionId); found && attempts.(int) >= maxInviteAttempts {
+		return "", "", InviteAttemptsErr
+	}
+	code = normalizeInviteCode(code)
+	gameId, found := s.Invites.Get(code)
+	if !found {
+		s.failInviteAttempt(sessionId)
+		return "", "", InviteCodeNotFoundErr
+	}
+	g, err := s.findActiveGame(gameId.(string))
+	if err != nil {
+		return "", "", InviteCodeNotFoundErr
+	}
+	playerId, err := s.seat(session, g, playerName)
+	if err != nil {
+		return "", "", err
+	}
+	s.Invites.Delete(code)
+	return g.Id, playerId, nil
+}
+
+// failInviteAttempt counts an unknown invite code tried by the session in the current window
+func (s *Server) failInviteAttempt(sessionId string) {
+	if err := s.InviteAttempts.Add(sessionId, 1, inviteAttemptWindow); err != nil {
+		// the count may have expired since, the next attempt starts a new window
+		s.InviteAttempts.IncrementInt(sessionId, 1)
+	}
+}
+
+// newInviteCode returns a random invite code
+func newInviteCode() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(inviteCodeAlphabet)))
+	for i := 0; i < inviteCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(inviteCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// normalizeInviteCode accepts codes typed in lower case or with separators
+func normalizeInviteCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// createInvite issue a new invite code for a private game waiting for a second player
+func (s *Server) createInvite() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		code, expiresAt, err := s.CreateInvite(sessionId, gameId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, GameIdNotMatchErr) || errors.Is(err, NoActiveGameInSessionErr) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if errors.Is(err, NotPrivateGameErr) || errors.Is(err, game.GameFilledWithMaxPlayerErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &CreateInviteResp{
+			InviteCode: code,
+			ExpiresAt:  expiresAt,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// joinGameByCode join the private game the invite code was issued for
+func (s *Server) joinGameByCode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		code, found := mux.Vars(r)["code"]
+		if !found {
+			http.Error(w, errors.New("invite code not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body JoinGameReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, fmt.Errorf("invalid request body, %w", err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		gameId, playerId, err := s.JoinGameByCode(sessionId, code, body.PlayerName)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, InviteCodeNotFoundErr) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if errors.Is(err, InviteAttemptsErr) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		} else if errors.Is(err, ActiveGameInSessionErr) || errors.Is(err, game.GameFilledWithMaxPlayerErr) ||
+			errors.Is(err, game.AlreadyJoinGameErr) || errors.Is(err, game.DuplicatePlayerNameErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &JoinGameByCodeResp{
+			GameId:   gameId,
+			PlayerId: playerId,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJoinGameByCode(t *testing.T) {
+	s := NewServer()
+	hostToken := newTestSession(t, s)
+	joinerToken := newTestSession(t, s)
+
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob", Private: true}), &created)
+	if len(created.InviteCode) != inviteCodeLength || created.InviteExpiresAt == nil {
+		t.Fatalf("expect an invite code with its expiry, got %+v", created)
+	}
+
+	// the private game is neither listed nor joined by its id
+	var open ListOpenGamesResp
+	decode(t, serve(t, s, http.MethodGet, "/games", joinerToken, nil), &open)
+	if len(open.Games) != 0 {
+		t.Errorf("expect no open games listed, got %v", open.Games)
+	}
+	if w := serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), joinerToken, JoinGameReq{PlayerName: "john"}); w.Code != http.StatusNotFound {
+		t.Errorf("joining a private game by id returns %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// friends type the code in any case
+	var joined JoinGameByCodeResp
+	typed := strings.ToLower(created.InviteCode[:3] + "-" + created.InviteCode[3:])
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/invites/%s/join", typed), joinerToken, JoinGameReq{PlayerName: "john"}), &joined)
+	if joined.GameId != created.GameId || joined.PlayerId == "" {
+		t.Errorf("expect to join the private game, got %+v", joined)
+	}
+	if w := serve(t, s, http.MethodPost, fmt.Sprintf("/invites/%s/join", created.InviteCode), newTestSession(t, s), JoinGameReq{PlayerName: "jane"}); w.Code != http.StatusNotFound {
+		t.Errorf("joining with a used code returns %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestCreateInvite(t *testing.T) {
+	s := NewServer(WithInviteTTL(time.Millisecond))
+	token := newTestSession(t, s)
+
+	var public CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", token, CreateNewGameReq{PlayerName: "bob"}), &public)
+	if public.InviteCode != "" {
+		t.Errorf("expect no invite code for a public game, got %q", public.InviteCode)
+	}
+	if w := serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/invite", public.GameId), token, nil); w.Code != http.StatusConflict {
+		t.Errorf("inviting to a public game returns %d, want %d", w.Code, http.StatusConflict)
+	}
+	serve(t, s, http.MethodDelete, fmt.Sprintf("/games/%s", public.GameId), token, EndGameReq{PlayerId: public.PlayerId})
+
+	// expired codes are replaced by new ones
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", token, CreateNewGameReq{PlayerName: "bob", Private: true}), &created)
+	time.Sleep(5 * time.Millisecond)
+	if w := serve(t, s, http.MethodPost, fmt.Sprintf("/invites/%s/join", created.InviteCode), newTestSession(t, s), JoinGameReq{PlayerName: "john"}); w.Code != http.StatusNotFound {
+		t.Errorf("joining with an expired code returns %d, want %d", w.Code, http.StatusNotFound)
+	}
+	var invite CreateInviteResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/invite", created.GameId), token, nil), &invite)
+	if invite.InviteCode == "" || invite.ExpiresAt.Before(*created.InviteExpiresAt) {
+		t.Errorf("expect a new invite code, got %+v", invite)
+	}
+}
+
+func TestJoinGameByCode_Attempts(t *testing.T) {
+	s := NewServer()
+	hostToken := newTestSession(t, s)
+	guesserToken := newTestSession(t, s)
+
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob", Private: true}), &created)
+	for i := 0; i < maxInviteAttempts; i++ {
+		if w := serve(t, s, http.MethodPost, "/invites/AAAAAAAA/join", guesserToken, JoinGameReq{PlayerName: "john"}); w.Code != http.StatusNotFound {
+			t.Fatalf("attempt %d with an unknown code returns %d, want %d", i, w.Code, http.StatusNotFound)
+		}
+	}
+
+	// the session is refused even with the right code until the window passes, other sessions are not
+	if w := serve(t, s, http.MethodPost, fmt.Sprintf("/invites/%s/join", created.InviteCode), guesserToken, JoinGameReq{PlayerName: "john"}); w.Code != http.StatusTooManyRequests {
+		t.Errorf("joining after too many attempts returns %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	var joined JoinGameByCodeResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/invites/%s/join", created.InviteCode), newTestSession(t, s), JoinGameReq{PlayerName: "jane"}), &joined)
+	if joined.GameId != created.GameId {
+		t.Errorf("expect another session to join with the code, got %+v", joined)
+	}
+}
+
+func TestNormalizeInviteCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "ABC234", want: "ABC234"},
+		{code: "abc234", want: "ABC234"},
+		{code: "abc-234", want: "ABC234"},
+		{code: " abc 234 ", want: "ABC234"},
+	}
+	for _, tt := range tests {
+		if got := normalizeInviteCode(tt.code); got != tt.want {
+			t.Errorf("normalizeInviteCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
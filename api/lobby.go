@@ -0,0 +1,239 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
+// page sizes of GET /games
+const (
+	DefaultOpenGamesLimit = 20
+	MaxOpenGamesLimit     = 100
+)
+
+// sort orders of GET /games, a leading minus sorts descending
+const (
+	SortNewest     = "-createdAt"
+	SortOldest     = "createdAt"
+	SortRatingAsc  = "rating"
+	SortRatingDesc = "-rating"
+)
+
+// predefined errors
+
+var (
+	InvalidSortErr   = fmt.Errorf("invalid sort. options: %s, %s, %s, %s", SortNewest, SortOldest, SortRatingAsc, SortRatingDesc)
+	InvalidCursorErr = errors.New("invalid cursor. use the nextCursor of the previous page with the same sort")
+	InvalidLimitErr  = fmt.Errorf("invalid limit. constraints: 0 < limit <= %d", MaxOpenGamesLimit)
+)
+
+// OpenGame public game waiting for a second player
+type OpenGame struct {
+	GameId   string `json:"gameId"`
+	HostName string `json:"hostName"`
+	Variant  string `json:"variant"`
+	// TimeControl nil for untimed games, same shape as in CreateNewGameReq
+	TimeControl *TimeControlReq `json:"timeControl,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	// Rating rating of the host
+	Rating float64 `json:"rating"`
+}
+
+// OpenGameFilter query of the open games list, zero values match all games
+type OpenGameFilter struct {
+	Variant string
+	// Timed true for games with a time control only, false for untimed games only
+	Timed     *bool
+	MinRating float64
+	MaxRating float64
+	// Sort one of the sort orders, defaults to SortNewest
+	Sort string
+	// Limit page size, defaults to DefaultOpenGamesLimit
+	Limit int
+	// Cursor nextCursor of the previous page, empty for the first page
+	Cursor string
+}
+
+// matches checks if the open game passes the filter
+func (f OpenGameFilter) matches(g OpenGame) bool {
+	if f.Variant != "" && g.Variant != f.Variant {
+		return false
+	}
+	if f.Timed != nil && *f.Timed != (g.TimeControl != nil) {
+		return false
+	}
+	if f.MinRating != 0 && g.Rating < f.MinRating {
+		return false
+	}
+	if f.MaxRating != 0 && g.Rating > f.MaxRating {
+		return false
+	}
+	return true
+}
+
+// OpenGameIndex open games by game id, kept up to date as games are created, joined and ended
+type OpenGameIndex struct {
+	mu    *sync.RWMutex
+	games map[string]OpenGame
+}
+
+// NewOpenGameIndex returns an empty index
+func NewOpenGameIndex() *OpenGameIndex {
+	return &OpenGameIndex{
+		mu:    &sync.RWMutex{},
+		games: map[string]OpenGame{},
+	}
+}
+
+// Add lists the game as open
+func (i *OpenGameIndex) Add(g OpenGame) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.games[g.GameId] = g
+}
+
+// Remove takes the game out of the open games, games not listed are ignored
+func (i *OpenGameIndex) Remove(gameId string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.games, gameId)
+}
+
+// List returns a page of the open games passing the filter in sort order and the cursor of the next page,
+// empty on the last page
+func (i *OpenGameIndex) List(f OpenGameFilter) ([]OpenGame, string, error) {
+	if f.Sort == "" {
+		f.Sort = SortNewest
+	}
+	compare, ok := openGameOrders[f.Sort]
+	if !ok {
+		return nil, "", InvalidSortErr
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultOpenGamesLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxOpenGamesLimit {
+		return nil, "", InvalidLimitErr
+	}
+	var after *OpenGame
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Sort, f.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &c
+	}
+
+	i.mu.RLock()
+	games := []OpenGame{}
+	for _, g := range i.games {
+		if f.matches(g) && (after == nil || compare(*after, g) < 0) {
+			games = append(games, g)
+		}
+	}
+	i.mu.RUnlock()
+
+	sort.Slice(games, func(a, b int) bool {
+		return compare(games[a], games[b]) < 0
+	})
+	if len(games) <= f.Limit {
+		return games, "", nil
+	}
+	games = games[:f.Limit]
+	return games, encodeCursor(f.Sort, games[len(games)-1]), nil
+}
+
+// openGameOrders compare functions of the sort orders, ties are broken by game id so pages never overlap
+var openGameOrders = map[string]func(a, b OpenGame) int{
+	SortNewest:     func(a, b OpenGame) int { return byGameId(compareTime(b.CreatedAt, a.CreatedAt), a, b) },
+	SortOldest:     func(a, b OpenGame) int { return byGameId(compareTime(a.CreatedAt, b.CreatedAt), a, b) },
+	SortRatingAsc:  func(a, b OpenGame) int { return byGameId(compareFloat(a.Rating, b.Rating), a, b) },
+	SortRatingDesc: func(a, b OpenGame) int { return byGameId(compareFloat(b.Rating, a.Rating), a, b) },
+}
+
+func byGameId(c int, a, b OpenGame) int {
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.GameId, b.GameId)
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// encodeCursor cursor pointing after the game, it holds the sort, the sort key and the game id
+func encodeCursor(sort string, g OpenGame) string {
+	key := strconv.FormatInt(g.CreatedAt.UnixNano(), 10)
+	if sort == SortRatingAsc || sort == SortRatingDesc {
+		key = strconv.FormatFloat(g.Rating, 'g', -1, 64)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join([]string{sort, key, g.GameId}, "|")))
+}
+
+// decodeCursor returns the game the cursor points after with its sort key set
+func decodeCursor(sort string, cursor string) (OpenGame, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return OpenGame{}, InvalidCursorErr
+	}
+	parts := strings.Split(string(b), "|")
+	if len(parts) != 3 || parts[0] != sort {
+		return OpenGame{}, InvalidCursorErr
+	}
+	g := OpenGame{GameId: parts[2]}
+	if sort == SortRatingAsc || sort == SortRatingDesc {
+		g.Rating, err = strconv.ParseFloat(parts[1], 64)
+	} else {
+		var nanos int64
+		nanos, err = strconv.ParseInt(parts[1], 10, 64)
+		g.CreatedAt = time.Unix(0, nanos)
+	}
+	if err != nil {
+		return OpenGame{}, InvalidCursorErr
+	}
+	return g, nil
+}
+
+// newOpenGame open game entry of a game just created by the host
+func newOpenGame(g *game.Game, rating float64, now time.Time) OpenGame {
+	o := OpenGame{
+		GameId:    g.Id,
+		HostName:  g.Player1Name,
+		Variant:   g.Rules().Name(),
+		CreatedAt: now,
+		Rating:    rating,
+	}
+	if tc := g.TimeControl; tc != nil {
+		o.TimeControl = &TimeControlReq{
+			PerMoveSeconds:   int(tc.PerMove / time.Second),
+			InitialSeconds:   int(tc.Initial / time.Second),
+			IncrementSeconds: int(tc.Increment / time.Second),
+		}
+	}
+	return o
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestOpenGameIndex_List(t *testing.T) {
+	now := time.Now()
+	index := NewOpenGameIndex()
+	for i, g := range []OpenGame{
+		{GameId: "a", Variant: "classic", Rating: 1400},
+		{GameId: "b", Variant: "gomoku", Rating: 1600, TimeControl: &TimeControlReq{PerMoveSeconds: 30}},
+		{GameId: "c", Variant: "classic", Rating: 1500},
+		{GameId: "d", Variant: "classic", Rating: 1500, TimeControl: &TimeControlReq{InitialSeconds: 300}},
+	} {
+		g.CreatedAt = now.Add(time.Duration(i) * time.Second)
+		index.Add(g)
+	}
+	timed := true
+	tests := []struct {
+		name    string
+		filter  OpenGameFilter
+		want    []string
+		wantErr error
+	}{
+		{name: "newest first", want: []string{"d", "c", "b", "a"}},
+		{name: "oldest first", filter: OpenGameFilter{Sort: SortOldest}, want: []string{"a", "b", "c", "d"}},
+		{name: "highest rating first, ties by game id", filter: OpenGameFilter{Sort: SortRatingDesc}, want: []string{"b", "c", "d", "a"}},
+		{name: "variant", filter: OpenGameFilter{Variant: "classic", Sort: SortOldest}, want: []string{"a", "c", "d"}},
+		{name: "timed", filter: OpenGameFilter{Timed: &timed}, want: []string{"d", "b"}},
+		{name: "rating range", filter: OpenGameFilter{MinRating: 1450, MaxRating: 1550}, want: []string{"d", "c"}},
+		{name: "unknown sort", filter: OpenGameFilter{Sort: "name"}, wantErr: InvalidSortErr},
+		{name: "limit too large", filter: OpenGameFilter{Limit: MaxOpenGamesLimit + 1}, wantErr: InvalidLimitErr},
+		{name: "garbage cursor", filter: OpenGameFilter{Cursor: "???"}, wantErr: InvalidCursorErr},
+		{name: "cursor of another sort", filter: OpenGameFilter{Cursor: encodeCursor(SortOldest, OpenGame{GameId: "a"})}, wantErr: InvalidCursorErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			games, _, err := index.List(tt.filter)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expect error %v, got %v", tt.wantErr, err)
+			}
+			var got []string
+			for _, g := range games {
+				got = append(got, g.GameId)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expect games %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// pages follow each other without gaps or overlaps
+	var got []string
+	filter := OpenGameFilter{Sort: SortRatingAsc, Limit: 3}
+	for page := 0; page < 3; page++ {
+		games, next, err := index.List(filter)
+		if err != nil {
+			t.Fatalf("unexpect error %s", err)
+		}
+		for _, g := range games {
+			got = append(got, g.GameId)
+		}
+		if next == "" {
+			break
+		}
+		filter.Cursor = next
+	}
+	if want := []string{"a", "c", "d", "b"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expect games %v over the pages, got %v", want, got)
+	}
+}
+
+func TestListOpenGames_Index(t *testing.T) {
+	s := NewServer()
+	hostToken := newTestSession(t, s)
+	otherToken := newTestSession(t, s)
+
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob", Variant: "gomoku", TimeControl: &TimeControlReq{PerMoveSeconds: 30}}), &created)
+	decode(t, serve(t, s, http.MethodPost, "/games", otherToken, CreateNewGameReq{PlayerName: "jane"}), &CreateNewGameResp{})
+
+	var open ListOpenGamesResp
+	decode(t, serve(t, s, http.MethodGet, "/games?variant=gomoku&timed=true", otherToken, nil), &open)
+	if len(open.Games) != 1 || open.Games[0].GameId != created.GameId || open.Games[0].HostName != "bob" ||
+		open.Games[0].Rating != DefaultRating || open.Games[0].TimeControl.PerMoveSeconds != 30 {
+		t.Fatalf("expect the gomoku game of bob, got %+v", open.Games)
+	}
+	if w := serve(t, s, http.MethodGet, "/games?limit=0", otherToken, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("limit 0 returns %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// joined games and games of ended sessions are no longer open
+	serve(t, s, http.MethodDelete, "/session", otherToken, nil)
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), newTestSession(t, s), JoinGameReq{PlayerName: "john"}), &JoinGameResp{})
+	decode(t, serve(t, s, http.MethodGet, "/games", hostToken, nil), &open)
+	if len(open.Games) != 0 {
+		t.Errorf("expect no open games, got %+v", open.Games)
+	}
+}
+
+func TestListOpenGames_ReplacedGame(t *testing.T) {
+	s := NewServer()
+	hostToken := newTestSession(t, s)
+
+	var first, second CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &first)
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &second)
+
+	var open ListOpenGamesResp
+	decode(t, serve(t, s, http.MethodGet, "/games", newTestSession(t, s), nil), &open)
+	if len(open.Games) != 1 || open.Games[0].GameId != second.GameId {
+		t.Errorf("expect only the new game of the session to be open, got %+v", open.Games)
+	}
+}
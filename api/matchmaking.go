@@ -0,0 +1,463 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
+// matchmaking rating window, it starts at the requested range around the player's rating and widens while waiting
+const (
+	DefaultRatingRange = 100.0
+	MaxRatingRange     = 1000.0
+	ratingWidening     = 50.0
+	ratingWidenEvery   = 10 * time.Second
+	matchmakingEvery   = time.Second
+)
+
+// queue statuses
+const (
+	QueueStatusQueued  = "queued"
+	QueueStatusMatched = "matched"
+)
+
+// predefined errors
+
+var (
+	AlreadyQueuedErr       = errors.New("already in the matchmaking queue. leave it with DELETE /matchmaking first")
+	NotQueuedErr           = errors.New("not in the matchmaking queue. enter it with POST /matchmaking")
+	InvalidRatingRangeErr  = fmt.Errorf("invalid rating range. constraints: 0 <= rating range <= %g", MaxRatingRange)
+	InvalidQueueVariantErr = errors.New("unknown game variant. see GET /variants")
+)
+
+// QueueTicket place of a session in the matchmaking queue, or the game it was matched into
+type QueueTicket struct {
+	Status string `json:"status"`
+	// Position 1 based place in the queue, the oldest ticket is first
+	Position int `json:"position,omitempty"`
+	// Variant variant to play, empty for any
+	Variant string  `json:"variant,omitempty"`
+	Rating  float64 `json:"rating"`
+	// MinRating and MaxRating window of opponent ratings accepted now, it widens while waiting
+	MinRating float64   `json:"minRating"`
+	MaxRating float64   `json:"maxRating"`
+	QueuedAt  time.Time `json:"queuedAt"`
+	// GameId and PlayerId of the game once matched, it is the active game of the session
+	GameId   string `json:"gameId,omitempty"`
+	PlayerId string `json:"playerId,omitempty"`
+
+	sessionId string
+	// accountId account of the session, empty for guests
+	accountId   string
+	playerName  string
+	ratingRange float64
+	// failed session ids of the tickets a game could not be started with, the pair is not tried again
+	failed map[string]bool
+	// matched closed once the ticket is matched
+	matched chan struct{}
+}
+
+// window returns the rating range accepted at now
+func (t *QueueTicket) window(now time.Time) float64 {
+	widened := t.ratingRange + ratingWidening*math.Floor(float64(now.Sub(t.QueuedAt))/float64(ratingWidenEvery))
+	return math.Min(widened, MaxRatingRange)
+}
+
+// accepts checks if the tickets can play each other at now, both rating windows must hold the other rating.
+// an account never plays itself
+func (t *QueueTicket) accepts(o *QueueTicket, now time.Time) bool {
+	if t.accountId != "" && t.accountId == o.accountId {
+		return false
+	}
+	if t.failed[o.sessionId] || o.failed[t.sessionId] {
+		return false
+	}
+	if t.Variant != "" && o.Variant != "" && t.Variant != o.Variant {
+		return false
+	}
+	diff := math.Abs(t.Rating - o.Rating)
+	return diff <= t.window(now) && diff <= o.window(now)
+}
+
+// Matchmaker queue of sessions waiting to be paired
+type Matchmaker struct {
+	mu *sync.Mutex
+	// waiting tickets in queue order
+	waiting []*QueueTicket
+	// tickets waiting or matched by session id
+	tickets map[string]*QueueTicket
+}
+
+// NewMatchmaker returns an empty queue
+func NewMatchmaker() *Matchmaker {
+	return &Matchmaker{
+		mu:      &sync.Mutex{},
+		tickets: map[string]*QueueTicket{},
+	}
+}
+
+// snapshot returns a copy of the ticket as seen at now, the caller holds the lock
+func (m *Matchmaker) snapshot(t *QueueTicket, now time.Time) QueueTicket {
+	c := *t
+	if c.Status == QueueStatusQueued {
+		for i, w := range m.waiting {
+			if w == t {
+				c.Position = i + 1
+			}
+		}
+	}
+	c.MinRating = t.Rating - t.window(now)
+	c.MaxRating = t.Rating + t.window(now)
+	return c
+}
+
+// EnterQueue puts the session in the matchmaking queue and pairs it right away if an opponent is waiting.
+// an empty variant plays any variant, rating range 0 uses DefaultRatingRange
+func (s *Server) EnterQueue(sessionId, playerName, variant string, ratingRange float64) (QueueTicket, error) {
+	session, err := s.authenticateSessionId(sessionId)
+	if err != nil {
+		return QueueTicket{}, err
+	}
+	if g, _ := session.Game(); g != nil {
+		return QueueTicket{}, ActiveGameInSessionErr
+	}
+	if variant != "" {
+		if _, err := game.LookupRules(variant); err != nil {
+			return QueueTicket{}, InvalidQueueVariantErr
+		}
+	}
+	if ratingRange < 0 || ratingRange > MaxRatingRange {
+		return QueueTicket{}, InvalidRatingRangeErr
+	}
+	if ratingRange == 0 {
+		ratingRange = DefaultRatingRange
+	}
+	now := time.Now()
+	m := s.Queue
+	m.mu.Lock()
+	if t, found := m.tickets[sessionId]; found && t.Status == QueueStatusQueued {
+		m.mu.Unlock()
+		return QueueTicket{}, AlreadyQueuedErr
+	}
+	t := &QueueTicket{
+		Status:      QueueStatusQueued,
+		Variant:     variant,
+		Rating:      s.rating(session),
+		QueuedAt:    now,
+		sessionId:   sessionId,
+		accountId:   session.AccountId,
+		playerName:  playerName,
+		ratingRange: ratingRange,
+		matched:     make(chan struct{}),
+	}
+	m.waiting = append(m.waiting, t)
+	m.tickets[sessionId] = t
+	m.mu.Unlock()
+
+	s.Matchmake(now)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshot(t, now), nil
+}
+
+// QueueStatus returns the ticket of the session, waiting up to timeout for a match while it is queued
+func (s *Server) QueueStatus(ctx context.Context, sessionId string, timeout time.Duration) (QueueTicket, error) {
+	if _, err := s.authenticateSessionId(sessionId); err != nil {
+		return QueueTicket{}, err
+	}
+	m := s.Queue
+	m.mu.Lock()
+	t, found := m.tickets[sessionId]
+	m.mu.Unlock()
+	if !found {
+		return QueueTicket{}, NotQueuedErr
+	}
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-t.matched:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshot(t, time.Now()), nil
+}
+
+// LeaveQueue takes the session out of the matchmaking queue
+func (s *Server) LeaveQueue(sessionId string) error {
+	if _, err := s.authenticateSessionId(sessionId); err != nil {
+		return err
+	}
+	m := s.Queue
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, found := m.tickets[sessionId]
+	if !found || t.Status != QueueStatusQueued {
+		return NotQueuedErr
+	}
+	m.remove(t)
+	delete(m.tickets, sessionId)
+	return nil
+}
+
+// remove takes the ticket out of the waiting tickets, the caller holds the lock
+func (m *Matchmaker) remove(t *QueueTicket) {
+	for i, w := range m.waiting {
+		if w == t {
+			m.waiting = append(m.waiting[:i], m.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// Matchmake pairs the waiting tickets whose rating windows at now accept each other, oldest tickets first, and
+// starts a game for every pair. tickets of sessions gone or busy in another game are dropped, a pair whose game
+// could not be started is not tried again.
+// returns the number of games started
+func (s *Server) Matchmake(now time.Time) int {
+	m := s.Queue
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// matched tickets are kept until their session is gone
+	for sessionId, t := range m.tickets {
+		if _, ok := s.Sessions.Load(sessionId); !ok && t.Status == QueueStatusMatched {
+			delete(m.tickets, sessionId)
+		}
+	}
+	var started int
+	for i := 0; i < len(m.waiting); i++ {
+		t := m.waiting[i]
+		if !s.available(t) {
+			m.remove(t)
+			delete(m.tickets, t.sessionId)
+			i--
+			continue
+		}
+		for j := i + 1; j < len(m.waiting); j++ {
+			o := m.waiting[j]
+			if !t.accepts(o, now) || !s.available(o) {
+				continue
+			}
+			err := s.startMatch(t, o)
+			if errors.Is(err, ActiveGameInSessionErr) {
+				// a session busy in another game by now leaves the queue, the other ticket keeps waiting
+				if !s.available(o) {
+					m.remove(o)
+					delete(m.tickets, o.sessionId)
+					j--
+				}
+				if !s.available(t) {
+					m.remove(t)
+					delete(m.tickets, t.sessionId)
+					i--
+					break
+				}
+				continue
+			} else if err != nil {
+				log.Printf("start matched game: %v", err)
+				if t.failed == nil {
+					t.failed = map[string]bool{}
+				}
+				t.failed[o.sessionId] = true
+				continue
+			}
+			m.remove(t)
+			m.remove(o)
+			i--
+			started++
+			break
+		}
+	}
+	return started
+}
+
+// available checks if the session of the ticket still exists and has no active game
+func (s *Server) available(t *QueueTicket) bool {
+	ss, ok := s.Sessions.Load(t.sessionId)
+	if !ok {
+		return false
+	}
+	g, _ := ss.(*Session).Game()
+	return g == nil
+}
+
+// startMatch creates the game of the older ticket, seats the newer one and notifies both, the caller holds the lock
+func (s *Server) startMatch(t *QueueTicket, o *QueueTicket) error {
+	variant := t.Variant
+	if variant == "" {
+		variant = o.Variant
+	}
+	hostName, joinerName := matchNames(t.playerName, o.playerName)
+	hostSession, _ := s.Sessions.Load(t.sessionId)
+	joinerSession, _ := s.Sessions.Load(o.sessionId)
+	// the sessions may have started other games since they were found available, they are never replaced
+	g, err := hostSession.(*Session).hostGame(&game.NewGameFactory{Variant: variant, OnFlagFall: s.flagFall}, hostName)
+	if err != nil {
+		return err
+	}
+	s.ActiveGames.Enter(hostSession.(*Session), g, g.Player1Id)
+	playerId, err := s.seat(joinerSession.(*Session), g, joinerName)
+	if err != nil {
+		hostSession.(*Session).leave(g)
+		s.ActiveGames.Leave(hostSession.(*Session), g)
+		return err
+	}
+	t.match(g.Id, g.Player1Id)
+	o.match(g.Id, playerId)
+	return nil
+}
+
+// matchNames names of the players of a matched game, players queued without a name play as "Player 1" and
+// "Player 2" and the joiner gets a suffix if both chose the same name
+func matchNames(host, joiner string) (string, string) {
+	if host == "" {
+		host = "Player 1"
+	}
+	if joiner == "" {
+		joiner = "Player 2"
+	}
+	if joiner == host {
+		joiner += " (2)"
+	}
+	return host, joiner
+}
+
+// match records the game the ticket was matched into and wakes up the sessions waiting for it
+func (t *QueueTicket) match(gameId, playerId string) {
+	t.Status = QueueStatusMatched
+	t.GameId = gameId
+	t.PlayerId = playerId
+	close(t.matched)
+}
+
+// runMatchmaker pairs waiting tickets every interval, widening their rating windows, until the server is closed
+func (s *Server) runMatchmaker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.Matchmake(now)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// enterQueue enter the matchmaking queue, the response tells if the session was matched right away
+func (s *Server) enterQueue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var body EnterQueueReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, fmt.Errorf("invalid request body, %w", err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		ticket, err := s.EnterQueue(sessionId, body.PlayerName, body.Variant, body.RatingRange)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, InvalidQueueVariantErr) || errors.Is(err, InvalidRatingRangeErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if errors.Is(err, AlreadyQueuedErr) || errors.Is(err, ActiveGameInSessionErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &MatchmakingResp{
+			QueueTicket: ticket,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// getQueueStatus queue position or matched game of the session. with the timeout query parameter the request waits
+// up to timeout for a match
+func (s *Server) getQueueStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		var timeout time.Duration
+		if v := r.URL.Query().Get("timeout"); v != "" {
+			timeout, err = time.ParseDuration(v)
+			if err != nil || timeout <= 0 || timeout > MaxWaitTimeout {
+				http.Error(w, fmt.Sprintf("invalid timeout query parameter. constraints: 0s < timeout <= %s", MaxWaitTimeout), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ticket, err := s.QueueStatus(r.Context(), sessionId, timeout)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, NotQueuedErr) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &MatchmakingResp{
+			QueueTicket: ticket,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// leaveQueue leave the matchmaking queue
+func (s *Server) leaveQueue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		err = s.LeaveQueue(sessionId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, NotQueuedErr) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestQueueTicket_Accepts(t *testing.T) {
+	queuedAt := time.Now()
+	tests := []struct {
+		name   string
+		ticket QueueTicket
+		other  QueueTicket
+		waited time.Duration
+		want   bool
+	}{
+		{
+			name:   "same rating",
+			ticket: QueueTicket{Rating: 1500, ratingRange: 100},
+			other:  QueueTicket{Rating: 1500, ratingRange: 100},
+			want:   true,
+		},
+		{
+			name:   "outside both windows",
+			ticket: QueueTicket{Rating: 1500, ratingRange: 100},
+			other:  QueueTicket{Rating: 1700, ratingRange: 100},
+			want:   false,
+		},
+		{
+			name:   "outside one window",
+			ticket: QueueTicket{Rating: 1500, ratingRange: 300},
+			other:  QueueTicket{Rating: 1700, ratingRange: 100},
+			want:   false,
+		},
+		{
+			name:   "windows widened while waiting",
+			ticket: QueueTicket{Rating: 1500, ratingRange: 100},
+			other:  QueueTicket{Rating: 1700, ratingRange: 100},
+			waited: 20 * time.Second,
+			want:   true,
+		},
+		{
+			name:   "widening is capped",
+			ticket: QueueTicket{Rating: 1000, ratingRange: 100},
+			other:  QueueTicket{Rating: 2500, ratingRange: 100},
+			waited: time.Hour,
+			want:   false,
+		},
+		{
+			name:   "different variants",
+			ticket: QueueTicket{Rating: 1500, ratingRange: 100, Variant: "classic"},
+			other:  QueueTicket{Rating: 1500, ratingRange: 100, Variant: "gomoku"},
+			want:   false,
+		},
+		{
+			name:   "same account",
+			ticket: QueueTicket{Rating: 1500, ratingRange: 100, accountId: "alice"},
+			other:  QueueTicket{Rating: 1500, ratingRange: 100, accountId: "alice"},
+			want:   false,
+		},
+		{
+			name:   "guests",
+			ticket: QueueTicket{Rating: 1500, ratingRange: 100},
+			other:  QueueTicket{Rating: 1500, ratingRange: 100},
+			want:   true,
+		},
+		{
+			name:   "failed pair",
+			ticket: QueueTicket{Rating: 1500, ratingRange: 100, sessionId: "a", failed: map[string]bool{"b": true}},
+			other:  QueueTicket{Rating: 1500, ratingRange: 100, sessionId: "b"},
+			want:   false,
+		},
+		{
+			name:   "any variant",
+			ticket: QueueTicket{Rating: 1500, ratingRange: 100},
+			other:  QueueTicket{Rating: 1500, ratingRange: 100, Variant: "gomoku"},
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.ticket.QueuedAt, tt.other.QueuedAt = queuedAt, queuedAt
+			if got := tt.ticket.accepts(&tt.other, queuedAt.Add(tt.waited)); got != tt.want {
+				t.Errorf("accepts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchmaking(t *testing.T) {
+	s := NewServer()
+	first := newTestSession(t, s)
+	second := newTestSession(t, s)
+	third := newTestSession(t, s)
+
+	var queued MatchmakingResp
+	decode(t, serve(t, s, http.MethodPost, "/matchmaking", first, EnterQueueReq{PlayerName: "bob", Variant: "gomoku"}), &queued)
+	if queued.Status != QueueStatusQueued || queued.Position != 1 || queued.MinRating != DefaultRating-DefaultRatingRange {
+		t.Fatalf("expect to wait first in the queue, got %+v", queued.QueueTicket)
+	}
+	if w := serve(t, s, http.MethodPost, "/matchmaking", first, EnterQueueReq{PlayerName: "bob"}); w.Code != http.StatusConflict {
+		t.Errorf("entering the queue twice returns %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	// a player of another variant waits behind, then cancels
+	decode(t, serve(t, s, http.MethodPost, "/matchmaking", third, EnterQueueReq{PlayerName: "jane", Variant: "classic"}), &queued)
+	if queued.Status != QueueStatusQueued || queued.Position != 2 {
+		t.Fatalf("expect to wait second in the queue, got %+v", queued.QueueTicket)
+	}
+	if w := serve(t, s, http.MethodDelete, "/matchmaking", third, nil); w.Code != http.StatusOK {
+		t.Errorf("leaving the queue returns %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := serve(t, s, http.MethodDelete, "/matchmaking", third, nil); w.Code != http.StatusNotFound {
+		t.Errorf("leaving the queue twice returns %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// a player of any variant is paired right away
+	var matched MatchmakingResp
+	decode(t, serve(t, s, http.MethodPost, "/matchmaking", second, EnterQueueReq{PlayerName: "john"}), &matched)
+	if matched.Status != QueueStatusMatched || matched.GameId == "" || matched.PlayerId == "" {
+		t.Fatalf("expect to be matched, got %+v", matched.QueueTicket)
+	}
+
+	// the waiting player learns about the game
+	var status MatchmakingResp
+	decode(t, serve(t, s, http.MethodGet, "/matchmaking?timeout=1s", first, nil), &status)
+	if status.Status != QueueStatusMatched || status.GameId != matched.GameId || status.PlayerId == matched.PlayerId {
+		t.Fatalf("expect to be matched into the same game, got %+v", status.QueueTicket)
+	}
+	var state GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, "/games/"+matched.GameId, first, nil), &state)
+	if state.Variant != "gomoku" || len(state.Players) != 2 || state.Players[0].Name != "bob" || state.Players[1].Name != "john" {
+		t.Errorf("expect bob and john to play gomoku, got %+v", state.GameView)
+	}
+	if w := serve(t, s, http.MethodGet, "/matchmaking", third, nil); w.Code != http.StatusNotFound {
+		t.Errorf("queue status without a ticket returns %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestMatchNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		joiner     string
+		wantHost   string
+		wantJoiner string
+	}{
+		{name: "named", host: "bob", joiner: "john", wantHost: "bob", wantJoiner: "john"},
+		{name: "guests without names", wantHost: "Player 1", wantJoiner: "Player 2"},
+		{name: "same name", host: "bob", joiner: "bob", wantHost: "bob", wantJoiner: "bob (2)"},
+		{name: "default name taken", host: "Player 2", wantHost: "Player 2", wantJoiner: "Player 2 (2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if host, joiner := matchNames(tt.host, tt.joiner); host != tt.wantHost || joiner != tt.wantJoiner {
+				t.Errorf("matchNames() = %q, %q, want %q, %q", host, joiner, tt.wantHost, tt.wantJoiner)
+			}
+		})
+	}
+}
+
+func TestMatchmaking_Guests(t *testing.T) {
+	s := NewServer()
+	first := newTestSession(t, s)
+	second := newTestSession(t, s)
+
+	decode(t, serve(t, s, http.MethodPost, "/matchmaking", first, EnterQueueReq{}), &MatchmakingResp{})
+	var matched MatchmakingResp
+	decode(t, serve(t, s, http.MethodPost, "/matchmaking", second, EnterQueueReq{}), &matched)
+	if matched.Status != QueueStatusMatched {
+		t.Fatalf("expect guests without names to be matched, got %+v", matched.QueueTicket)
+	}
+	var state GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, "/games/"+matched.GameId, second, nil), &state)
+	if len(state.Players) != 2 || state.Players[0].Name != "Player 1" || state.Players[1].Name != "Player 2" {
+		t.Errorf("expect default player names, got %+v", state.Players)
+	}
+}
+
+func TestMatchmaking_SameAccount(t *testing.T) {
+	s := NewServer()
+	decode(t, serve(t, s, http.MethodPost, "/accounts", "", CredentialsReq{Username: "alice", Password: "correct horse"}), &AccountResp{})
+	var first, second LoginResp
+	decode(t, serve(t, s, http.MethodPost, "/login", "", CredentialsReq{Username: "alice", Password: "correct horse"}), &first)
+	decode(t, serve(t, s, http.MethodPost, "/login", "", CredentialsReq{Username: "alice", Password: "correct horse"}), &second)
+
+	decode(t, serve(t, s, http.MethodPost, "/matchmaking", first.Token, EnterQueueReq{}), &MatchmakingResp{})
+	var queued MatchmakingResp
+	decode(t, serve(t, s, http.MethodPost, "/matchmaking", second.Token, EnterQueueReq{}), &queued)
+	if queued.Status != QueueStatusQueued || queued.Position != 2 {
+		t.Fatalf("expect an account not to be matched against itself, got %+v", queued.QueueTicket)
+	}
+	if started := s.Matchmake(time.Now()); started != 0 {
+		t.Errorf("Matchmake() started %d games, want 0", started)
+	}
+}
+
+func TestMatchmaking_BusySession(t *testing.T) {
+	s := NewServer()
+	hostToken := newTestSession(t, s)
+	joinerToken := newTestSession(t, s)
+	hostSessionId, _ := s.Tokens.Verify(hostToken)
+	joinerSessionId, _ := s.Tokens.Verify(joinerToken)
+
+	// the host started a game of its own after it was found available
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &created)
+	host := &QueueTicket{sessionId: hostSessionId, matched: make(chan struct{})}
+	joiner := &QueueTicket{sessionId: joinerSessionId, matched: make(chan struct{})}
+	if err := s.startMatch(host, joiner); err != ActiveGameInSessionErr {
+		t.Fatalf("startMatch() error = %v, want %v", err, ActiveGameInSessionErr)
+	}
+	var info GetCurrentSessionResp
+	decode(t, serve(t, s, http.MethodGet, "/session", hostToken, nil), &info)
+	if info.GameId != created.GameId {
+		t.Errorf("expect the game of the host to be kept, got %q", info.GameId)
+	}
+	decode(t, serve(t, s, http.MethodGet, "/session", joinerToken, nil), &info)
+	if info.GameId != "" || joiner.Status == QueueStatusMatched {
+		t.Errorf("expect the joiner not to be matched, got game %q", info.GameId)
+	}
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
+// DefaultRating rating of players without rated games
+const DefaultRating = 1500.0
+
+// Glicko-2 parameters, every rated game is a rating period of its own
+const (
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+	// glickoTau constrains the change in volatility over time
+	glickoTau = 0.5
+	// glickoScale converts between the Glicko and the Glicko-2 scale
+	glickoScale = 173.7178
+	// glickoEpsilon convergence tolerance of the volatility iteration
+	glickoEpsilon = 0.000001
+)
+
+// Rating Glicko-2 rating of an account, the deviation shrinks as the player plays more rated games
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+	// Games number of rated games played
+	Games int `json:"games"`
+}
+
+// RatingChange rating of an account after a rated game
+type RatingChange struct {
+	GameId string `json:"gameId"`
+	// OpponentId account id of the opponent
+	OpponentId string `json:"opponentId"`
+	// Score 1 for a win, 0.5 for a draw and 0 for a loss
+	Score  float64   `json:"score"`
+	Rating Rating    `json:"rating"`
+	Change float64   `json:"change"`
+	Time   time.Time `json:"time"`
+}
+
+// glickoResult result of a game against an opponent, score 1 for a win, 0.5 for a draw and 0 for a loss
+type glickoResult struct {
+	opponent Rating
+	score    float64
+}
+
+// newRating rating of a player without rated games
+func newRating() Rating {
+	return Rating{Rating: DefaultRating, Deviation: DefaultDeviation, Volatility: DefaultVolatility}
+}
+
+// update returns the rating after the results of a rating period, see Glickman's "Example of the Glicko-2 system"
+func (r Rating) update(results []glickoResult) Rating {
+	mu := (r.Rating - DefaultRating) / glickoScale
+	phi := r.Deviation / glickoScale
+
+	// estimated variance v and improvement delta from the game outcomes
+	var vInv, sum float64
+	for _, res := range results {
+		muJ := (res.opponent.Rating - DefaultRating) / glickoScale
+		g := glickoG(res.opponent.Deviation / glickoScale)
+		e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+		vInv += g * g * e * (1 - e)
+		sum += g * (res.score - e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	// new volatility with the Illinois algorithm
+	a := math.Log(r.Volatility * r.Volatility)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoEpsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	volatility := math.Exp(A / 2)
+
+	phiStar := math.Sqrt(phi*phi + volatility*volatility)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*sum
+	return Rating{
+		Rating:     newMu*glickoScale + DefaultRating,
+		Deviation:  math.Min(newPhi*glickoScale, DefaultDeviation),
+		Volatility: volatility,
+		Games:      r.Games + len(results),
+	}
+}
+
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// RatingService ratings and rating history of accounts, updated when rated games finish
+type RatingService struct {
+	mu      *sync.RWMutex
+	ratings map[string]Rating
+	// history rating changes by account id, oldest first
+	history map[string][]RatingChange
+	// rated games already counted, a game is only rated once
+	rated map[string]bool
+}
+
+// NewRatingService returns a service where every account has the default rating
+func NewRatingService() *RatingService {
+	return &RatingService{
+		mu:      &sync.RWMutex{},
+		ratings: map[string]Rating{},
+		history: map[string][]RatingChange{},
+		rated:   map[string]bool{},
+	}
+}
+
+// Get returns the rating of the account, the default rating for accounts without rated games
+func (rs *RatingService) Get(accountId string) Rating {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	if r, found := rs.ratings[accountId]; found {
+		return r
+	}
+	return newRating()
+}
+
+// History returns the rating changes of the account, newest first
+func (rs *RatingService) History(accountId string) []RatingChange {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	changes := rs.history[accountId]
+	history := make([]RatingChange, 0, len(changes))
+	for i := len(changes) - 1; i >= 0; i-- {
+		history = append(history, changes[i])
+	}
+	return history
+}
+
+// Record updates the ratings of both players of a finished rated game and shows the changes in the game.
+// games without a result, such as games abandoned before the first move, do not count.
+// returns false if the game was not rated
+func (rs *RatingService) Record(g *game.Game) bool {
+	if !g.Rated() {
+		return false
+	}
+	v := g.View()
+	var score float64
+	switch {
+	case v.Status == game.StatusDraw:
+		score = 0.5
+	case v.Winner == 1:
+		score = 1
+	case v.Winner == 2:
+		score = 0
+	default:
+		return false
+	}
+	now := time.Now()
+	p1, p2 := v.Players[0].AccountId, v.Players[1].AccountId
+
+	rs.mu.Lock()
+	if rs.rated[g.Id] {
+		rs.mu.Unlock()
+		return false
+	}
+	rs.rated[g.Id] = true
+	r1, r2 := rs.ratingOf(p1), rs.ratingOf(p2)
+	// both ratings are updated from the ratings before the game
+	n1 := r1.update([]glickoResult{{opponent: r2, score: score}})
+	n2 := r2.update([]glickoResult{{opponent: r1, score: 1 - score}})
+	rs.ratings[p1], rs.ratings[p2] = n1, n2
+	rs.history[p1] = append(rs.history[p1], RatingChange{GameId: g.Id, OpponentId: p2, Score: score, Rating: n1, Change: n1.Rating - r1.Rating, Time: now})
+	rs.history[p2] = append(rs.history[p2], RatingChange{GameId: g.Id, OpponentId: p1, Score: 1 - score, Rating: n2, Change: n2.Rating - r2.Rating, Time: now})
+	rs.mu.Unlock()
+
+	g.SetRating(1, game.PlayerRating{Rating: r1.Rating, Deviation: r1.Deviation, Change: n1.Rating - r1.Rating})
+	g.SetRating(2, game.PlayerRating{Rating: r2.Rating, Deviation: r2.Deviation, Change: n2.Rating - r2.Rating})
+	return true
+}
+
+// ratingOf the caller holds the lock
+func (rs *RatingService) ratingOf(accountId string) Rating {
+	if r, found := rs.ratings[accountId]; found {
+		return r
+	}
+	return newRating()
+}
+
+// showRatings shows the current ratings of both players once a rated game has its two players
+func (s *Server) showRatings(g *game.Game) {
+	if !g.Rated() {
+		return
+	}
+	for player, accountId := range []string{g.Player1AccountId, g.Player2AccountId} {
+		r := s.Ratings.Get(accountId)
+		g.SetRating(player+1, game.PlayerRating{Rating: r.Rating, Deviation: r.Deviation})
+	}
+}
+
+// rating returns the rating of the session's player, guests have the default rating
+func (s *Server) rating(session *Session) float64 {
+	if session.AccountId == "" {
+		return DefaultRating
+	}
+	return s.Ratings.Get(session.AccountId).Rating
+}
+
+// getRatingHistory rating changes of an account, newest first
+func (s *Server) getRatingHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId, found := mux.Vars(r)["accountId"]
+		if !found {
+			http.Error(w, errors.New("account id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := s.Accounts.Get(accountId); errors.Is(err, AccountNotFoundErr) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &RatingHistoryResp{
+			Rating:  s.Ratings.Get(accountId),
+			History: s.Ratings.History(accountId),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
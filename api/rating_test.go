@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestRating_Update(t *testing.T) {
+	tests := []struct {
+		name    string
+		rating  Rating
+		results []glickoResult
+		want    Rating
+	}{
+		{
+			// the example of Glickman's "Example of the Glicko-2 system"
+			name:   "paper example",
+			rating: Rating{Rating: 1500, Deviation: 200, Volatility: 0.06},
+			results: []glickoResult{
+				{opponent: Rating{Rating: 1400, Deviation: 30}, score: 1},
+				{opponent: Rating{Rating: 1550, Deviation: 100}, score: 0},
+				{opponent: Rating{Rating: 1700, Deviation: 300}, score: 0},
+			},
+			want: Rating{Rating: 1464.06, Deviation: 151.52, Volatility: 0.05999, Games: 3},
+		},
+		{
+			name:    "new players win",
+			rating:  newRating(),
+			results: []glickoResult{{opponent: newRating(), score: 1}},
+			want:    Rating{Rating: 1662.31, Deviation: 290.32, Volatility: 0.06, Games: 1},
+		},
+		{
+			name:    "new players draw",
+			rating:  newRating(),
+			results: []glickoResult{{opponent: newRating(), score: 0.5}},
+			want:    Rating{Rating: 1500, Deviation: 290.32, Volatility: 0.06, Games: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rating.update(tt.results)
+			if math.Abs(got.Rating-tt.want.Rating) > 0.01 || math.Abs(got.Deviation-tt.want.Deviation) > 0.01 ||
+				math.Abs(got.Volatility-tt.want.Volatility) > 0.00001 || got.Games != tt.want.Games {
+				t.Errorf("update() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatedGame(t *testing.T) {
+	s := NewServer()
+	var alice, bob LoginResp
+	for name, login := range map[string]*LoginResp{"alice": &alice, "bob": &bob} {
+		decode(t, serve(t, s, http.MethodPost, "/accounts", "", CredentialsReq{Username: name, Password: "correct horse"}), &AccountResp{})
+		decode(t, serve(t, s, http.MethodPost, "/login", "", CredentialsReq{Username: name, Password: "correct horse"}), login)
+	}
+
+	// an abandoned game without moves has no result and does not count
+	var created CreateNewGameResp
+	var joined JoinGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", alice.Token, CreateNewGameReq{}), &created)
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), bob.Token, JoinGameReq{}), &joined)
+	if w := serve(t, s, http.MethodDelete, "/games/"+created.GameId, alice.Token, EndGameReq{PlayerId: created.PlayerId}); w.Code != http.StatusOK {
+		t.Fatalf("ending the game returns %d, body %s", w.Code, w.Body.String())
+	}
+	var history RatingHistoryResp
+	decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/accounts/%s/ratings", alice.AccountId), "", nil), &history)
+	if len(history.History) != 0 || history.Rating.Rating != DefaultRating {
+		t.Fatalf("expect no rated games, got %+v", history)
+	}
+
+	// alice wins the top row
+	decode(t, serve(t, s, http.MethodPost, "/games", alice.Token, CreateNewGameReq{}), &created)
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), bob.Token, JoinGameReq{}), &joined)
+	var state GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, "/games/"+created.GameId, bob.Token, nil), &state)
+	if !state.Rated || state.Players[1].Rating == nil || state.Players[1].Rating.Rating != DefaultRating {
+		t.Fatalf("expect a rated game showing the ratings, got %+v", state.GameView)
+	}
+	moves := []struct {
+		token    string
+		playerId string
+		row, col int
+	}{
+		{alice.Token, created.PlayerId, 0, 0},
+		{bob.Token, joined.PlayerId, 1, 0},
+		{alice.Token, created.PlayerId, 0, 1},
+		{bob.Token, joined.PlayerId, 1, 1},
+		{alice.Token, created.PlayerId, 0, 2},
+	}
+	for _, m := range moves {
+		decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/play", created.GameId), m.token, PlayMoveReq{PlayerId: m.playerId, Row: m.row, Column: m.col}), &PlayMoveResp{})
+	}
+
+	decode(t, serve(t, s, http.MethodGet, "/games/"+created.GameId, bob.Token, nil), &state)
+	if state.Winner != 1 || state.Players[0].Rating.Change <= 0 || state.Players[1].Rating.Change >= 0 {
+		t.Errorf("expect alice to gain and bob to lose rating, got %+v and %+v", state.Players[0].Rating, state.Players[1].Rating)
+	}
+	var profile AccountResp
+	decode(t, serve(t, s, http.MethodGet, "/accounts/"+bob.AccountId, "", nil), &profile)
+	if profile.Rating.Rating >= DefaultRating || profile.Rating.Games != 1 || profile.Rating.Deviation >= DefaultDeviation {
+		t.Errorf("expect bob's rating to drop after one game, got %+v", profile.Rating)
+	}
+	decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/accounts/%s/ratings", alice.AccountId), "", nil), &history)
+	if len(history.History) != 1 || history.History[0].GameId != created.GameId || history.History[0].OpponentId != bob.AccountId ||
+		history.History[0].Score != 1 || history.History[0].Rating != history.Rating {
+		t.Errorf("expect the won game in alice's history, got %+v", history)
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
+// DefaultSessionIdleTTL sessions without an authenticated request for this long are reaped
+const DefaultSessionIdleTTL = 30 * time.Minute
+
+// Metrics counters of the server, safe for concurrent use
+type Metrics struct {
+	// SessionsReaped sessions removed after being idle for longer than the idle ttl
+	SessionsReaped atomic.Int64
+	// GamesReaped active games ended because an idle session was reaped
+	GamesReaped atomic.Int64
+}
+
+// WithSessionIdleTTL changes how long a session may stay idle before it is reaped, defaults to DefaultSessionIdleTTL.
+// the reaper checks for idle sessions every half ttl, a ttl of 0 turns it off
+func WithSessionIdleTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.SessionIdleTTL = ttl
+	}
+}
+
+// ReapIdleSessions ends the active game of every session idle since before now minus the idle ttl, moving it to
+// the finished games, and removes the session. returns the number of sessions reaped
+func (s *Server) ReapIdleSessions(now time.Time) int {
+	deadline := now.Add(-s.SessionIdleTTL)
+	var reaped int
+	s.Sessions.Range(func(sessionId, v any) bool {
+		session := v.(*Session)
+		if !session.LastActivity().Before(deadline) {
+			return true
+		}
+		if g, playerId := session.Game(); g != nil {
+			s.reapGame(sessionId.(string), playerId, g)
+			s.ActiveGames.Leave(session, g)
+		}
+		s.Sessions.Delete(sessionId)
+		s.ActiveGames.Forget(session)
+		reaped++
+		return true
+	})
+	if reaped > 0 {
+		s.Metrics.SessionsReaped.Add(int64(reaped))
+		log.Printf("reaped %d idle sessions", reaped)
+	}
+	return reaped
+}
+
+// reapGame the player of a reaped session abandons the game, it is moved to the finished games of both players.
+// the end event tells clients the game was abandoned by its reason
+func (s *Server) reapGame(sessionId string, playerId string, g *game.Game) {
+	if err := s.abandonGame(sessionId, playerId, g); err != nil {
+		log.Printf("end game %s of idle session: %v", g.Id, err)
+		return
+	}
+	s.Metrics.GamesReaped.Add(1)
+}
+
+// abandonGame the player leaves the game for good, it ends as abandoned and is moved to the finished games of both
+// players
+func (s *Server) abandonGame(sessionId string, playerId string, g *game.Game) error {
+	if err := g.EndGame(g.Id, playerId); err != nil {
+		return err
+	}
+	s.finishGame(sessionId, g)
+	s.publish(EventEnd, g)
+	return nil
+}
+
+// runReaper reaps idle sessions every interval until the server is closed
+func (s *Server) runReaper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.ReapIdleSessions(now)
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
+func TestReapIdleSessions(t *testing.T) {
+	s := NewServer(WithSessionIdleTTL(time.Hour))
+	defer s.Close()
+	idleToken := newTestSession(t, s)
+	activeToken := newTestSession(t, s)
+	newTestSession(t, s)
+
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", idleToken, CreateNewGameReq{PlayerName: "bob"}), &created)
+	var joined JoinGameResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), activeToken, JoinGameReq{PlayerName: "john"}), &joined)
+
+	events, unsubscribe := s.Events.Subscribe("")
+	defer unsubscribe()
+
+	// only the idle session and the session without requests since creation are reaped
+	idleSessionId, _ := s.Tokens.Verify(idleToken)
+	activeSessionId, _ := s.Tokens.Verify(activeToken)
+	now := time.Now().Add(2 * time.Hour)
+	s.Sessions.Range(func(sessionId, v any) bool {
+		if sessionId == activeSessionId {
+			v.(*Session).touch(now)
+		}
+		return true
+	})
+	if reaped := s.ReapIdleSessions(now); reaped != 2 {
+		t.Errorf("ReapIdleSessions() = %d, want 2", reaped)
+	}
+	if _, found := s.Sessions.Load(idleSessionId); found {
+		t.Error("expect the idle session to be removed")
+	}
+
+	// the game of the reaped session was abandoned before the first move and is visible to the opponent
+	select {
+	case e := <-events:
+		if e.Type != EventEnd || e.GameId != created.GameId || e.State.Reason != game.EndReasonAbandon {
+			t.Errorf("expect an end event for the abandoned game, got %s %+v", e.Type, e.State)
+		}
+	default:
+		t.Error("expect the abandoned game to be published")
+	}
+	var state GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s", created.GameId), activeToken, nil), &state)
+	if state.Status != game.StatusAbandoned || state.Reason != game.EndReasonAbandon {
+		t.Errorf("expect the reaped game to be finished, got status %q", state.Status)
+	}
+	var info GetCurrentSessionResp
+	decode(t, serve(t, s, http.MethodGet, "/session", activeToken, nil), &info)
+	if info.GameId != "" {
+		t.Errorf("expect no active game left, got %q", info.GameId)
+	}
+	if w := serve(t, s, http.MethodGet, "/session", idleToken, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("expect the reaped session to be unauthorized, got %d", w.Code)
+	}
+
+	var metrics GetMetricsResp
+	decode(t, serve(t, s, http.MethodGet, "/metrics", "", nil), &metrics)
+	if metrics.SessionsReaped != 2 || metrics.GamesReaped != 1 || metrics.ActiveSessions != 1 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+}
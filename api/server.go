@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/patrickmn/go-cache"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
 )
 
 type Server struct {
@@ -19,33 +23,137 @@ type Server struct {
 	// FinishedGames a cache with 1 min default expiration and purges expired items every 2 mins
 	// store finsihed games with key being sessionId + '_' + gameId
 	FinishedGames *cache.Cache
+	// Tokens signs and verifies the bearer tokens sessions are authenticated with
+	Tokens *TokenSigner
+	// SessionIdleTTL sessions idle for longer are reaped in the background
+	SessionIdleTTL time.Duration
+	Metrics        *Metrics
+	// Events game state changes published by the server methods, feeding real time clients
+	Events *EventBus
+	// Invites invite codes of private games with the game id as value, they expire after InviteTTL
+	Invites   *cache.Cache
+	InviteTTL time.Duration
+	// InviteAttempts unknown invite codes tried by session id in the current attempt window
+	InviteAttempts *cache.Cache
+	// Accounts registered players, sessions are signed in to them with POST /login
+	Accounts *AccountStore
+	// Ratings Glicko-2 ratings of accounts, updated as rated games finish
+	Ratings *RatingService
+	// OpenGames public games waiting for a second player, listed by GET /games
+	OpenGames *OpenGameIndex
+	// ActiveGames games played in the sessions by game id, and sessions by player id
+	ActiveGames *ActiveGameIndex
+	// Queue sessions waiting to be paired with an opponent
+	Queue *Matchmaker
+	// joinMu serializes joins so a game never takes two second players
+	joinMu *sync.Mutex
+	// stop stops the background reaper and matchmaker
+	stop chan struct{}
+}
+
+// long poll timeouts of GET /games/{gameId}/wait
+const (
+	DefaultWaitTimeout = 30 * time.Second
+	MaxWaitTimeout     = 2 * time.Minute
+)
+
+// Option configures the server
+type Option func(*Server)
+
+// WithTokenSigningKey signs session tokens with the key instead of a random key generated at start up,
+// tokens then stay valid across restarts and between servers sharing the key
+func WithTokenSigningKey(key []byte) Option {
+	return func(s *Server) {
+		s.Tokens = NewTokenSigner(key, s.Tokens.ttl)
+	}
+}
+
+// WithTokenTTL changes how long session tokens are valid, defaults to DefaultTokenTTL
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.Tokens = NewTokenSigner(s.Tokens.key, ttl)
+	}
 }
 
-func NewServer() *Server {
+func NewServer(options ...Option) *Server {
 	s := &Server{
-		Router:        mux.NewRouter(),
-		Sessions:      &sync.Map{},
-		FinishedGames: cache.New(1*time.Minute, 2*time.Minute),
+		Router:         mux.NewRouter(),
+		Sessions:       &sync.Map{},
+		FinishedGames:  cache.New(1*time.Minute, 2*time.Minute),
+		Tokens:         NewTokenSigner(nil, DefaultTokenTTL),
+		SessionIdleTTL: DefaultSessionIdleTTL,
+		Metrics:        &Metrics{},
+		Events:         NewEventBus(DefaultEventLogSize),
+		Invites:        cache.New(cache.NoExpiration, time.Minute),
+		InviteTTL:      DefaultInviteTTL,
+		InviteAttempts: cache.New(inviteAttemptWindow, time.Minute),
+		Queue:          NewMatchmaker(),
+		OpenGames:      NewOpenGameIndex(),
+		ActiveGames:    NewActiveGameIndex(),
+		Accounts:       NewAccountStore(),
+		Ratings:        NewRatingService(),
+		joinMu:         &sync.Mutex{},
+		stop:           make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
 	}
 	s.routes()
+	if s.SessionIdleTTL > 0 {
+		go s.runReaper(s.SessionIdleTTL / 2)
+	}
+	go s.runMatchmaker(matchmakingEvery)
 	return s
 }
 
+// Close stops the background work of the server
+func (s *Server) Close() {
+	close(s.stop)
+}
+
 func (s *Server) routes() {
 	s.HandleFunc("/session", s.createNewSession()).Methods("POST")
 	s.HandleFunc("/session", s.getCurrentSession()).Methods("GET")
 	s.HandleFunc("/session", s.endSession()).Methods("DELETE")
+	s.HandleFunc("/session/refresh", s.refreshSession()).Methods("POST")
+	s.HandleFunc("/session/stream-token", s.createStreamToken()).Methods("POST")
+	s.HandleFunc("/accounts", s.register()).Methods("POST")
+	s.HandleFunc("/accounts/{accountId}", s.getAccount()).Methods("GET")
+	s.HandleFunc("/accounts/{accountId}/ratings", s.getRatingHistory()).Methods("GET")
+	s.HandleFunc("/login", s.login()).Methods("POST")
 
 	// game handlers
 	s.HandleFunc("/games", s.createNewGame()).Methods("POST")
 	s.HandleFunc("/games", s.listOpenGames()).Methods("GET")
 	s.HandleFunc("/games/{gameId}", s.getGameState()).Methods("GET")
+	s.HandleFunc("/games/{gameId}/analysis", s.analyzeGame()).Methods("GET")
+	s.HandleFunc("/games/{gameId}/moves", s.getMoves()).Methods("GET")
+	s.HandleFunc("/games/{gameId}/replay", s.replayGame()).Methods("GET")
+	s.HandleFunc("/games/{gameId}/wait", s.waitForChange()).Methods("GET")
 	s.HandleFunc("/games/{gameId}/join", s.joinGame()).Methods("POST")
+	s.HandleFunc("/games/{gameId}/invite", s.createInvite()).Methods("POST")
+	s.HandleFunc("/invites/{code}/join", s.joinGameByCode()).Methods("POST")
 	s.HandleFunc("/games/{gameId}/play", s.playMove()).Methods("POST")
+	s.HandleFunc("/games/{gameId}/color", s.chooseColor()).Methods("POST")
+	s.HandleFunc("/games/{gameId}/takeback", s.requestTakeback()).Methods("POST")
+	s.HandleFunc("/games/{gameId}/takeback/accept", s.respondTakeback(true)).Methods("POST")
+	s.HandleFunc("/games/{gameId}/takeback/decline", s.respondTakeback(false)).Methods("POST")
+	s.HandleFunc("/games/{gameId}/resign", s.resign()).Methods("POST")
+	s.HandleFunc("/games/{gameId}/draw", s.offerDraw()).Methods("POST")
+	s.HandleFunc("/games/{gameId}/draw/accept", s.respondDraw(true)).Methods("POST")
+	s.HandleFunc("/games/{gameId}/draw/decline", s.respondDraw(false)).Methods("POST")
+	s.HandleFunc("/games/{gameId}/rematch", s.rematch()).Methods("POST")
 	s.HandleFunc("/games/{gameId}", s.endGame()).Methods("DELETE")
+	s.HandleFunc("/games/{gameId}/ws", s.gameWebSocket()).Methods("GET")
+	s.HandleFunc("/matchmaking", s.enterQueue()).Methods("POST")
+	s.HandleFunc("/matchmaking", s.getQueueStatus()).Methods("GET")
+	s.HandleFunc("/matchmaking", s.leaveQueue()).Methods("DELETE")
+	s.HandleFunc("/variants", s.listVariants()).Methods("GET")
+	s.HandleFunc("/metrics", s.getMetrics()).Methods("GET")
+	s.HandleFunc("/events", s.streamEvents()).Methods("GET")
 }
 
-// createNewSession create a new session (should return a token/ID which can be used for authentication)
+// createNewSession create a new session, returns the session id and a signed bearer token to authenticate with
 func (s *Server) createNewSession() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -54,8 +162,15 @@ func (s *Server) createNewSession() http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		token, expiresAt, err := s.Tokens.Sign(sid)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		var resp = &CreateNewSessionResp{
 			SessionId: sid,
+			Token:     token,
+			ExpiresAt: expiresAt,
 		}
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -68,12 +183,12 @@ func (s *Server) createNewSession() http.HandlerFunc {
 // getCurrentSession get current session ID and active/open game ID
 func (s *Server) getCurrentSession() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sessionId := r.Header.Get("Authorization")
-		if sessionId == "" {
-			http.Error(w, errors.New("no sessionId found in header authorization").Error(), http.StatusUnauthorized)
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
-		sessionId, gameId, err := s.GetSessionInfo(sessionId)
+		sessionId, gameId, accountId, err := s.GetSessionInfo(sessionId)
 		if errors.Is(err, SessionIdAuthErr) {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
@@ -84,6 +199,66 @@ func (s *Server) getCurrentSession() http.HandlerFunc {
 		var resp = &GetCurrentSessionResp{
 			SessionId: sessionId,
 			GameId:    gameId,
+			AccountId: accountId,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// refreshSession issue a new token for the session of a valid token
+func (s *Server) refreshSession() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		token, expiresAt, err := s.RefreshToken(sessionId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &RefreshSessionResp{
+			SessionId: sessionId,
+			Token:     token,
+			ExpiresAt: expiresAt,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// createStreamToken issue a short-lived token to open websockets and event streams with the token query parameter
+func (s *Server) createStreamToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		token, expiresAt, err := s.StreamToken(sessionId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &StreamTokenResp{
+			Token:     token,
+			ExpiresAt: expiresAt,
 		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -97,12 +272,12 @@ func (s *Server) getCurrentSession() http.HandlerFunc {
 // endSession delete session
 func (s *Server) endSession() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sessionId := r.Header.Get("Authorization")
-		if sessionId == "" {
-			http.Error(w, errors.New("no sessionId found in header authorization").Error(), http.StatusUnauthorized)
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
-		err := s.DeleteSession(sessionId)
+		err = s.DeleteSession(sessionId)
 		if errors.Is(err, SessionIdAuthErr) {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
@@ -117,9 +292,9 @@ func (s *Server) endSession() http.HandlerFunc {
 // createNewGame create a new game (sets the current game ID for authenticated session)
 func (s *Server) createNewGame() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sessionId := r.Header.Get("Authorization")
-		if sessionId == "" {
-			http.Error(w, errors.New("no sessionId found in header authorization").Error(), http.StatusUnauthorized)
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 		var body CreateNewGameReq
@@ -129,10 +304,31 @@ func (s *Server) createNewGame() http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		gameId, playerId, err := s.CreateGame(sessionId, body.PlayerName)
+		gameFactory := &game.NewGameFactory{
+			Variant:        body.Variant,
+			Size:           body.BoardSize,
+			WinLength:      body.WinLength,
+			ExactWinLength: body.ExactWinLength,
+			Opening:        body.Opening,
+			Bot:            body.Bot,
+			Private:        body.Private,
+		}
+		if tc := body.TimeControl; tc != nil {
+			gameFactory.TimeControl = &game.TimeControl{
+				PerMove:   time.Duration(tc.PerMoveSeconds) * time.Second,
+				Initial:   time.Duration(tc.InitialSeconds) * time.Second,
+				Increment: time.Duration(tc.IncrementSeconds) * time.Second,
+			}
+		}
+		gameId, playerId, err := s.CreateGame(sessionId, body.PlayerName, gameFactory)
 		if errors.Is(err, SessionIdAuthErr) {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
+		} else if errors.Is(err, game.UnknownVariantErr) || errors.Is(err, game.InvalidBoardSizeErr) ||
+			errors.Is(err, game.InvalidWinLengthErr) || errors.Is(err, game.InvalidOpeningErr) || errors.Is(err, game.UnknownBotLevelErr) ||
+			errors.Is(err, game.InvalidTimeControlErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		} else if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -141,6 +337,16 @@ func (s *Server) createNewGame() http.HandlerFunc {
 			GameId:   gameId,
 			PlayerId: playerId,
 		}
+		// friends join private games with the invite code, games against a bot have no seat left
+		if body.Private && body.Bot == "" {
+			code, expiresAt, err := s.CreateInvite(sessionId, gameId)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			resp.InviteCode = code
+			resp.InviteExpiresAt = &expiresAt
+		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -150,12 +356,69 @@ func (s *Server) createNewGame() http.HandlerFunc {
 	}
 }
 
-// listOpenGames list open games in all sessions
+// listOpenGames list public games waiting for a second player. query parameters: variant, timed, minRating,
+// maxRating, sort, limit and cursor
 func (s *Server) listOpenGames() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		openGames := s.ListOpenGames()
+		query := r.URL.Query()
+		filter := OpenGameFilter{
+			Variant: query.Get("variant"),
+			Sort:    query.Get("sort"),
+			Cursor:  query.Get("cursor"),
+		}
+		if v := query.Get("timed"); v != "" {
+			timed, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "invalid timed query parameter. options: true, false", http.StatusBadRequest)
+				return
+			}
+			filter.Timed = &timed
+		}
+		for name, value := range map[string]*float64{"minRating": &filter.MinRating, "maxRating": &filter.MaxRating} {
+			if v := query.Get(name); v != "" {
+				rating, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("invalid %s query parameter, %s", name, err), http.StatusBadRequest)
+					return
+				}
+				*value = rating
+			}
+		}
+		if v := query.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit <= 0 {
+				http.Error(w, InvalidLimitErr.Error(), http.StatusBadRequest)
+				return
+			}
+			filter.Limit = limit
+		}
+
+		games, nextCursor, err := s.ListOpenGames(filter)
+		if errors.Is(err, InvalidSortErr) || errors.Is(err, InvalidCursorErr) || errors.Is(err, InvalidLimitErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		var resp = &ListOpenGamesResp{
-			SessionIdAndGameIds: openGames,
+			Games:      games,
+			NextCursor: nextCursor,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// listVariants list the game variants that can be chosen when creating a game
+func (s *Server) listVariants() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var resp = &ListVariantsResp{
+			Variants: game.Variants(),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -166,12 +429,34 @@ func (s *Server) listOpenGames() http.HandlerFunc {
 	}
 }
 
-// getGameState get the game state
+// getMetrics get the server metrics
+func (s *Server) getMetrics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var activeSessions int
+		s.Sessions.Range(func(_, _ any) bool {
+			activeSessions++
+			return true
+		})
+		var resp = &GetMetricsResp{
+			ActiveSessions: activeSessions,
+			SessionsReaped: s.Metrics.SessionsReaped.Load(),
+			GamesReaped:    s.Metrics.GamesReaped.Load(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// getGameState get the game state as json, as text with the Accept: text/plain header or ?format=text
 func (s *Server) getGameState() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sessionId := r.Header.Get("Authorization")
-		if sessionId == "" {
-			http.Error(w, errors.New("no sessionId found in header authorization").Error(), http.StatusUnauthorized)
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 		gameId, found := mux.Vars(r)["gameId"]
@@ -180,7 +465,7 @@ func (s *Server) getGameState() http.HandlerFunc {
 			return
 		}
 
-		state, err := s.GetGameState(sessionId, gameId)
+		g, err := s.GetGameState(sessionId, gameId)
 		if errors.Is(err, SessionIdAuthErr) {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
@@ -188,8 +473,188 @@ func (s *Server) getGameState() http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if writeTextState(w, r, sessionId, g) {
+			return
+		}
 		var resp = &GetGameStateResp{
-			State: state,
+			GameView: g.View(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// analyzeGame evaluate every empty position of the game under perfect play
+func (s *Server) analyzeGame() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		g, analysis, err := s.AnalyzeGame(sessionId, gameId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, game.AnalysisTooLargeErr) || errors.Is(err, game.AnalysisInOpeningErr) || errors.Is(err, game.AnalysisNotSupportedErr) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		v := g.View()
+		var resp = &AnalyzeGameResp{
+			GameId: g.Id,
+			End:    v.Turn == 0,
+			Moves:  analysis,
+		}
+		if v.Turn > 0 && v.Turn <= len(v.Players) {
+			resp.PlayerToMove = v.Players[v.Turn-1].Name
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// getMoves list the moves played in the game in order
+func (s *Server) getMoves() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		moves, err := s.GetMoves(sessionId, gameId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &GetMovesResp{
+			GameId: gameId,
+			Moves:  moves,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// replayGame reconstruct the game at the ply given by the ply query parameter, the latest position without it
+func (s *Server) replayGame() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+		var ply *int
+		if v := r.URL.Query().Get("ply"); v != "" {
+			p, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, fmt.Errorf("invalid ply query parameter, %w", err).Error(), http.StatusBadRequest)
+				return
+			}
+			ply = &p
+		}
+
+		g, err := s.ReplayGame(sessionId, gameId, ply)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, game.InvalidPlyErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &ReplayGameResp{
+			GameId:    gameId,
+			Ply:       len(g.Moves),
+			Board:     g.Board,
+			MetaBoard: g.MetaBoard,
+			State:     g.ShowGameState(sessionId),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// waitForChange long poll the game, answers with the game state once its version moves past the since query
+// parameter or after the timeout query parameter (e.g. 10s, defaults to DefaultWaitTimeout)
+func (s *Server) waitForChange() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+		since, err := strconv.Atoi(r.URL.Query().Get("since"))
+		if err != nil {
+			http.Error(w, fmt.Errorf("invalid since query parameter, the game version to wait past is required, %w", err).Error(), http.StatusBadRequest)
+			return
+		}
+		timeout := DefaultWaitTimeout
+		if v := r.URL.Query().Get("timeout"); v != "" {
+			timeout, err = time.ParseDuration(v)
+			if err != nil || timeout <= 0 || timeout > MaxWaitTimeout {
+				http.Error(w, fmt.Sprintf("invalid timeout query parameter. constraints: 0s < timeout <= %s", MaxWaitTimeout), http.StatusBadRequest)
+				return
+			}
+		}
+
+		g, changed, err := s.WaitForChange(r.Context(), sessionId, gameId, since, timeout)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp = &WaitForChangeResp{
+			Changed:  changed,
+			GameView: g.View(),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -203,9 +668,9 @@ func (s *Server) getGameState() http.HandlerFunc {
 // joinGame join an open game (sets the current game ID for the authenticated session)
 func (s *Server) joinGame() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sessionId := r.Header.Get("Authorization")
-		if sessionId == "" {
-			http.Error(w, errors.New("no sessionId found in header authorization").Error(), http.StatusUnauthorized)
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 		gameId, found := mux.Vars(r)["gameId"]
@@ -226,6 +691,13 @@ func (s *Server) joinGame() http.HandlerFunc {
 		if errors.Is(err, SessionIdAuthErr) {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
+		} else if errors.Is(err, game.GameIdNotfoundErr) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if errors.Is(err, ActiveGameInSessionErr) || errors.Is(err, game.GameFilledWithMaxPlayerErr) ||
+			errors.Is(err, game.AlreadyJoinGameErr) || errors.Is(err, game.DuplicatePlayerNameErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
 		} else if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -245,9 +717,9 @@ func (s *Server) joinGame() http.HandlerFunc {
 // playMove play a legal move
 func (s *Server) playMove() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sessionId := r.Header.Get("Authorization")
-		if sessionId == "" {
-			http.Error(w, errors.New("no sessionId found in header authorization").Error(), http.StatusUnauthorized)
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 		gameId, found := mux.Vars(r)["gameId"]
@@ -264,16 +736,22 @@ func (s *Server) playMove() http.HandlerFunc {
 			return
 		}
 
-		state, err := s.PlayMove(sessionId, gameId, body.PlayerId, body.Row, body.Column)
+		g, err := s.PlayMove(sessionId, gameId, body.PlayerId, body.SubBoard, body.Row, body.Column)
 		if errors.Is(err, SessionIdAuthErr) {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
+		} else if errors.Is(err, game.TimeUpErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
 		} else if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if writeTextState(w, r, sessionId, g) {
+			return
+		}
 		var resp = &PlayMoveResp{
-			State: state,
+			GameView: g.View(),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -284,12 +762,12 @@ func (s *Server) playMove() http.HandlerFunc {
 	}
 }
 
-// endGame end game
-func (s *Server) endGame() http.HandlerFunc {
+// chooseColor choose a color or to place two more stones during the swap2 opening
+func (s *Server) chooseColor() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sessionId := r.Header.Get("Authorization")
-		if sessionId == "" {
-			http.Error(w, errors.New("no sessionId found in header authorization").Error(), http.StatusUnauthorized)
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 		gameId, found := mux.Vars(r)["gameId"]
@@ -298,22 +776,385 @@ func (s *Server) endGame() http.HandlerFunc {
 			return
 		}
 
-		var body EndGameReq
+		var body ChooseColorReq
 		d := json.NewDecoder(r.Body)
 		d.DisallowUnknownFields()
 		if err := d.Decode(&body); err != nil {
-			http.Error(w, fmt.Errorf("invalid request body, %w", err).Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		err := s.EndGame(sessionId, gameId, body.PlayerId)
+		g, err := s.ChooseColor(sessionId, gameId, body.PlayerId, game.ColorChoice(body.Choice))
 		if errors.Is(err, SessionIdAuthErr) {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
+		} else if errors.Is(err, game.TimeUpErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if errors.Is(err, game.InvalidColorChoiceErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		} else if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		return
+		if writeTextState(w, r, sessionId, g) {
+			return
+		}
+		var resp = &ChooseColorResp{
+			GameView: g.View(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// requestTakeback ask the opponent to take back the player's last move
+func (s *Server) requestTakeback() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body TakebackReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		g, err := s.RequestTakeback(sessionId, gameId, body.PlayerId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, game.TakebackPendingErr) || errors.Is(err, game.NoMoveToTakeBackErr) || errors.Is(err, game.GameAlreadyFinishedErr) ||
+			errors.Is(err, game.TimeUpErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if writeTextState(w, r, sessionId, g) {
+			return
+		}
+		var resp = &TakebackResp{
+			GameView: g.View(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// respondTakeback accept or decline the opponent's takeback request
+func (s *Server) respondTakeback(accept bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body TakebackReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		g, err := s.RespondTakeback(sessionId, gameId, body.PlayerId, accept)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, game.NoTakebackRequestErr) || errors.Is(err, game.OwnTakebackRequestErr) || errors.Is(err, game.GameAlreadyFinishedErr) ||
+			errors.Is(err, game.TimeUpErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if writeTextState(w, r, sessionId, g) {
+			return
+		}
+		var resp = &TakebackResp{
+			GameView: g.View(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// resign resign the game, the opponent wins
+func (s *Server) resign() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body ResignReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		g, err := s.Resign(sessionId, gameId, body.PlayerId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, game.NoOpponentErr) || errors.Is(err, game.GameAlreadyFinishedErr) || errors.Is(err, game.TimeUpErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if writeTextState(w, r, sessionId, g) {
+			return
+		}
+		var resp = &ResignResp{
+			GameView: g.View(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// offerDraw offer the opponent a draw, accepts the opponent's offer if there is one
+func (s *Server) offerDraw() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body DrawReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		g, err := s.OfferDraw(sessionId, gameId, body.PlayerId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, game.NoOpponentErr) || errors.Is(err, game.DrawOfferPendingErr) || errors.Is(err, game.BotDeclinesDrawErr) ||
+			errors.Is(err, game.GameAlreadyFinishedErr) || errors.Is(err, game.TimeUpErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if writeTextState(w, r, sessionId, g) {
+			return
+		}
+		var resp = &DrawResp{
+			GameView: g.View(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// respondDraw accept or decline the opponent's draw offer
+func (s *Server) respondDraw(accept bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body DrawReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		g, err := s.RespondDraw(sessionId, gameId, body.PlayerId, accept)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, game.NoDrawOfferErr) || errors.Is(err, game.OwnDrawOfferErr) || errors.Is(err, game.GameAlreadyFinishedErr) ||
+			errors.Is(err, game.TimeUpErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if writeTextState(w, r, sessionId, g) {
+			return
+		}
+		var resp = &DrawResp{
+			GameView: g.View(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// rematch ask for a rematch of the finished game, the response shows the rematch game id once both players asked
+func (s *Server) rematch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body RematchReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		g, err := s.Rematch(sessionId, gameId, body.PlayerId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, GameIdNotMatchErr) || errors.Is(err, NoActiveGameInSessionErr) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if errors.Is(err, game.GameNotFinishedErr) || errors.Is(err, game.NoOpponentErr) || errors.Is(err, game.RematchPendingErr) ||
+			errors.Is(err, game.RematchAlreadyCreatedErr) || errors.Is(err, ActiveGameInSessionErr) || errors.Is(err, OpponentUnavailableErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if writeTextState(w, r, sessionId, g) {
+			return
+		}
+		var resp = &RematchResp{
+			GameView: g.View(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// endGame abandon the game, the opponent wins once the game started
+func (s *Server) endGame() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body EndGameReq
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&body); err != nil {
+			http.Error(w, fmt.Errorf("invalid request body, %w", err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = s.EndGame(sessionId, gameId, body.PlayerId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, game.GameAlreadyFinishedErr) || errors.Is(err, game.TimeUpErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+}
+
+// writeTextState writes the text rendering of the game if the client asked for text
+// with the Accept: text/plain header or the format=text query parameter, returns false otherwise
+func writeTextState(w http.ResponseWriter, r *http.Request, sessionId string, g *game.Game) bool {
+	if r.URL.Query().Get("format") != "text" && !strings.Contains(r.Header.Get("Accept"), "text/plain") {
+		return false
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := fmt.Fprint(w, g.ShowGameState(sessionId)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
+	return true
 }
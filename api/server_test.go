@@ -9,6 +9,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
 )
 
 func TestCreateNewSession(t *testing.T) {
@@ -29,9 +32,14 @@ func TestCreateNewSession(t *testing.T) {
 
 func TestGetCurrentSession_AuthError(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/session", nil)
-	req.Header.Set("Authorization", "abc")
+	s := NewServer()
+	token, _, err := s.Tokens.Sign("abc")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
 	w := httptest.NewRecorder()
-	NewServer().getCurrentSession()(w, req)
+	s.getCurrentSession()(w, req)
 	res := w.Result()
 	defer res.Body.Close()
 	data, err := io.ReadAll(res.Body)
@@ -57,11 +65,10 @@ func TestGetCurrentSession_Success(t *testing.T) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sessionId := resp.SessionId
-	expected := fmt.Sprintf("{\"sessionId\":\"%s\",\"gameId\":\"\"}\n", sessionId)
-	// call get current session with sessionId
+	expected := fmt.Sprintf("{\"sessionId\":\"%s\",\"gameId\":\"\"}\n", resp.SessionId)
+	// call get current session with the session token
 	req2 := httptest.NewRequest(http.MethodGet, "/session", nil)
-	req2.Header.Set("Authorization", sessionId)
+	req2.Header.Set("Authorization", "Bearer "+resp.Token)
 	w2 := httptest.NewRecorder()
 	s.getCurrentSession()(w2, req2)
 	res2 := w2.Result()
@@ -78,9 +85,14 @@ func TestGetCurrentSession_Success(t *testing.T) {
 
 func TestCreateNewGame_AuthErr(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/games", nil)
-	req.Header.Set("Authorization", "abc")
+	s := NewServer()
+	token, _, err := s.Tokens.Sign("abc")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
 	w := httptest.NewRecorder()
-	NewServer().getCurrentSession()(w, req)
+	s.getCurrentSession()(w, req)
 	res := w.Result()
 	defer res.Body.Close()
 	data, err := io.ReadAll(res.Body)
@@ -105,7 +117,7 @@ func TestCreateNewGame_Success(t *testing.T) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sessionId := resp.SessionId
+	token := resp.Token
 
 	// create game in the session
 	var body = CreateNewGameReq{
@@ -117,7 +129,7 @@ func TestCreateNewGame_Success(t *testing.T) {
 		t.Errorf("unexpect error %s", err.Error())
 	}
 	req2 := httptest.NewRequest(http.MethodPost, "/games", &buf)
-	req2.Header.Set("Authorization", sessionId)
+	req2.Header.Set("Authorization", "Bearer "+token)
 	w2 := httptest.NewRecorder()
 	s.createNewGame()(w2, req2)
 	res2 := w2.Result()
@@ -131,6 +143,40 @@ func TestCreateNewGame_Success(t *testing.T) {
 	}
 }
 
+func TestCreateNewGame_InvalidBoardSize(t *testing.T) {
+	// create a session
+	var s = NewServer()
+	req := httptest.NewRequest(http.MethodPost, "/session", nil)
+	w := httptest.NewRecorder()
+	s.createNewSession()(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+	var resp CreateNewSessionResp
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Errorf("unexpect error %s", err.Error())
+	}
+	token := resp.Token
+
+	// create game with a board too large
+	var body = CreateNewGameReq{
+		PlayerName: "bob",
+		BoardSize:  100,
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		t.Errorf("unexpect error %s", err.Error())
+	}
+	req2 := httptest.NewRequest(http.MethodPost, "/games", &buf)
+	req2.Header.Set("Authorization", "Bearer "+token)
+	w2 := httptest.NewRecorder()
+	s.createNewGame()(w2, req2)
+	res2 := w2.Result()
+	defer res2.Body.Close()
+	if res2.StatusCode != http.StatusBadRequest {
+		t.Errorf("expect status %d, got %d", http.StatusBadRequest, res2.StatusCode)
+	}
+}
+
 func TestListOpenGames(t *testing.T) {
 	// create a session
 	var s = NewServer()
@@ -144,7 +190,7 @@ func TestListOpenGames(t *testing.T) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sessionId := resp.SessionId
+	token := resp.Token
 
 	// create game in the session
 	var body = CreateNewGameReq{
@@ -156,13 +202,13 @@ func TestListOpenGames(t *testing.T) {
 		t.Errorf("unexpect error %s", err.Error())
 	}
 	req2 := httptest.NewRequest(http.MethodPost, "/games", &buf)
-	req2.Header.Set("Authorization", sessionId)
+	req2.Header.Set("Authorization", "Bearer "+token)
 	w2 := httptest.NewRecorder()
 	s.createNewGame()(w2, req2)
 
 	// check list open games
 	req3 := httptest.NewRequest(http.MethodGet, "/games", &buf)
-	req3.Header.Set("Authorization", sessionId)
+	req3.Header.Set("Authorization", "Bearer "+token)
 	w3 := httptest.NewRecorder()
 	s.listOpenGames()(w3, req3)
 	res3 := w3.Result()
@@ -171,7 +217,424 @@ func TestListOpenGames(t *testing.T) {
 	if err := json.NewDecoder(res3.Body).Decode(&newResp); err != nil {
 		t.Errorf("unexpect error %s", err.Error())
 	}
-	if len(newResp.SessionIdAndGameIds) != 1 {
+	if len(newResp.Games) != 1 || newResp.Games[0].HostName != "bob" {
 		t.Error("expect 1 open game in the session")
 	}
 }
+
+func TestPlayMove_BotReplies(t *testing.T) {
+	// create a session
+	var s = NewServer()
+	req := httptest.NewRequest(http.MethodPost, "/session", nil)
+	w := httptest.NewRecorder()
+	s.createNewSession()(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+	var resp CreateNewSessionResp
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Errorf("unexpect error %s", err.Error())
+	}
+	token := resp.Token
+
+	// create a game against the minimax bot
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(CreateNewGameReq{PlayerName: "bob", Bot: "minimax"}); err != nil {
+		t.Errorf("unexpect error %s", err.Error())
+	}
+	req2 := httptest.NewRequest(http.MethodPost, "/games", &buf)
+	req2.Header.Set("Authorization", "Bearer "+token)
+	w2 := httptest.NewRecorder()
+	s.ServeHTTP(w2, req2)
+	var newResp CreateNewGameResp
+	if err := json.NewDecoder(w2.Result().Body).Decode(&newResp); err != nil {
+		t.Errorf("unexpect error %s", err.Error())
+	}
+
+	// play a move, the bot replies in the same request
+	buf.Reset()
+	if err := json.NewEncoder(&buf).Encode(PlayMoveReq{PlayerId: newResp.PlayerId, Row: 0, Column: 0}); err != nil {
+		t.Errorf("unexpect error %s", err.Error())
+	}
+	req3 := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/games/%s/play", newResp.GameId), &buf)
+	req3.Header.Set("Authorization", "Bearer "+token)
+	w3 := httptest.NewRecorder()
+	s.ServeHTTP(w3, req3)
+	var playResp PlayMoveResp
+	if err := json.NewDecoder(w3.Result().Body).Decode(&playResp); err != nil {
+		t.Errorf("unexpect error %s", err.Error())
+	}
+	var marks int
+	for _, row := range playResp.Board {
+		for _, mark := range row {
+			if mark != nil {
+				marks++
+			}
+		}
+	}
+	if playResp.Status != game.StatusInProgress || playResp.Turn != 1 || marks != 2 || *playResp.Board[0][0] != "X" {
+		t.Errorf("expect the bot to reply, got state %+v", playResp.GameView)
+	}
+
+	// the text rendering is still available
+	req4 := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/games/%s?format=text", newResp.GameId), nil)
+	req4.Header.Set("Authorization", "Bearer "+token)
+	w4 := httptest.NewRecorder()
+	s.ServeHTTP(w4, req4)
+	data, err := io.ReadAll(w4.Result().Body)
+	if err != nil {
+		t.Errorf("unexpect error %s", err.Error())
+	}
+	if !strings.HasPrefix(w4.Header().Get("Content-Type"), "text/plain") || !strings.Contains(string(data), "O | ") || !strings.Contains(string(data), "bob Turn") {
+		t.Errorf("expect the text rendering, got %s", string(data))
+	}
+}
+
+func TestGetGameState_TextWhilePlaying(t *testing.T) {
+	s := NewServer()
+	token := newTestSession(t, s)
+	spectatorToken := newTestSession(t, s)
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", token, CreateNewGameReq{PlayerName: "bob", Variant: "gomoku", Bot: "random"}), &created)
+
+	// a spectator reads the text rendering while the host and the bot play
+	stop := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s?format=text", created.GameId), spectatorToken, nil)
+			}
+		}
+	}()
+	for row := 0; row < 5; row++ {
+		for col := 0; col < 15; col += 5 {
+			serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/play", created.GameId), token, PlayMoveReq{PlayerId: created.PlayerId, Row: row, Column: col})
+		}
+	}
+	close(stop)
+	<-polled
+}
+
+func TestDeleteSession_AbandonsGame(t *testing.T) {
+	s := NewServer()
+	hostToken := newTestSession(t, s)
+	joinerToken := newTestSession(t, s)
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &created)
+	var joined JoinGameResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), joinerToken, JoinGameReq{PlayerName: "john"}), &joined)
+	events, unsubscribe := s.Events.Subscribe(created.GameId)
+	defer unsubscribe()
+
+	// the host leaves, the opponent sees the game abandoned and is free to play again
+	if w := serve(t, s, http.MethodDelete, "/session", hostToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("ending the session returns %d", w.Code)
+	}
+	var state GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, "/games/"+created.GameId, joinerToken, nil), &state)
+	if state.Status != game.StatusAbandoned || state.Reason != game.EndReasonAbandon {
+		t.Errorf("expect the game to be abandoned, got %+v", state.GameView)
+	}
+	var info GetCurrentSessionResp
+	decode(t, serve(t, s, http.MethodGet, "/session", joinerToken, nil), &info)
+	if info.GameId != "" {
+		t.Errorf("expect no active game for the opponent, got %q", info.GameId)
+	}
+	select {
+	case e := <-events:
+		if e.Type != EventEnd {
+			t.Errorf("got %s event, want the end event", e.Type)
+		}
+	default:
+		t.Error("expect the abandoned game to be published")
+	}
+}
+
+// serve sends a request with the bearer token and the json encoded body to the server
+func serve(t *testing.T, s *Server, method string, target string, token string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("unexpect error %s", err.Error())
+		}
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+// decode decodes the json response body into resp
+func decode(t *testing.T, w *httptest.ResponseRecorder, resp any) {
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpect status %d, body %s", w.Code, w.Body.String())
+	}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Fatalf("unexpect error %s", err.Error())
+	}
+}
+
+// newTestSession creates a session and returns its token
+func newTestSession(t *testing.T, s *Server) string {
+	var resp CreateNewSessionResp
+	decode(t, serve(t, s, http.MethodPost, "/session", "", nil), &resp)
+	return resp.Token
+}
+
+func TestJoinGame_OwnSession(t *testing.T) {
+	s := NewServer()
+	hostToken := newTestSession(t, s)
+	joinerToken := newTestSession(t, s)
+
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &created)
+	if w := serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), hostToken, JoinGameReq{PlayerName: "john"}); w.Code != http.StatusConflict {
+		t.Errorf("expect status %d for the host joining its own game, got %d", http.StatusConflict, w.Code)
+	}
+	var joined JoinGameResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), joinerToken, JoinGameReq{PlayerName: "john"}), &joined)
+
+	// both sessions report the game
+	for _, token := range []string{hostToken, joinerToken} {
+		var info GetCurrentSessionResp
+		decode(t, serve(t, s, http.MethodGet, "/session", token, nil), &info)
+		if info.GameId != created.GameId {
+			t.Errorf("session %s reports game %q, want %q", info.SessionId, info.GameId, created.GameId)
+		}
+	}
+
+	// a third player can not take the seat
+	w := serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), newTestSession(t, s), JoinGameReq{PlayerName: "alice"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expect status %d for a full game, got %d", http.StatusConflict, w.Code)
+	}
+	w = serve(t, s, http.MethodPost, "/games/unknown/join", newTestSession(t, s), JoinGameReq{PlayerName: "alice"})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expect status %d for an unknown game, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// each player moves from its own session, the first column wins for bob
+	moves := []struct {
+		token    string
+		playerId string
+		row      int
+	}{
+		{hostToken, created.PlayerId, 0},
+		{joinerToken, joined.PlayerId, 0},
+		{hostToken, created.PlayerId, 1},
+		{joinerToken, joined.PlayerId, 1},
+		{hostToken, created.PlayerId, 2},
+	}
+	for i, m := range moves {
+		var played PlayMoveResp
+		decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/play", created.GameId), m.token, PlayMoveReq{PlayerId: m.playerId, Row: m.row, Column: i % 2}), &played)
+	}
+
+	// the result is visible from both sessions and neither has an active game left
+	for _, token := range []string{hostToken, joinerToken} {
+		var state GetGameStateResp
+		decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s", created.GameId), token, nil), &state)
+		var info GetCurrentSessionResp
+		decode(t, serve(t, s, http.MethodGet, "/session", token, nil), &info)
+		if state.Status != game.StatusWon || state.Winner != 1 {
+			t.Errorf("session %s sees status %q winner %d, want player 1 won", info.SessionId, state.Status, state.Winner)
+		}
+		if info.GameId != "" {
+			t.Errorf("session %s still reports game %q", info.SessionId, info.GameId)
+		}
+	}
+}
+
+func TestWaitForChange(t *testing.T) {
+	s := NewServer()
+	hostToken := newTestSession(t, s)
+	joinerToken := newTestSession(t, s)
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &created)
+	var joined JoinGameResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), joinerToken, JoinGameReq{PlayerName: "john"}), &joined)
+	var state GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s", created.GameId), joinerToken, nil), &state)
+
+	// nothing changes, the wait times out
+	var timedOut WaitForChangeResp
+	decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s/wait?since=%d&timeout=10ms", created.GameId, state.Version), joinerToken, nil), &timedOut)
+	if timedOut.Changed || timedOut.Version != state.Version {
+		t.Errorf("expect the wait to time out, got changed %v version %d", timedOut.Changed, timedOut.Version)
+	}
+
+	// the joiner waits for the host to move
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s/wait?since=%d&timeout=5s", created.GameId, state.Version), joinerToken, nil)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/play", created.GameId), hostToken, PlayMoveReq{PlayerId: created.PlayerId, Row: 1, Column: 1}), &PlayMoveResp{})
+	select {
+	case w := <-done:
+		var waited WaitForChangeResp
+		decode(t, w, &waited)
+		if !waited.Changed || waited.Version <= state.Version || waited.Turn != 2 || waited.Board[1][1] == nil {
+			t.Errorf("expect the move in the wait response, got %+v", waited)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expect the wait to return after the move")
+	}
+
+	for _, target := range []string{"/wait", "/wait?since=x", "/wait?since=0&timeout=1h"} {
+		if w := serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s%s", created.GameId, target), joinerToken, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s returns %d, want %d", target, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestCreateNewGame_TimeControl(t *testing.T) {
+	s := NewServer()
+	token := newTestSession(t, s)
+
+	for _, tc := range []TimeControlReq{{}, {PerMoveSeconds: 30, InitialSeconds: 60}, {PerMoveSeconds: 30, IncrementSeconds: 2}} {
+		w := serve(t, s, http.MethodPost, "/games", token, CreateNewGameReq{PlayerName: "bob", TimeControl: &tc})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("time control %+v returns %d, want %d", tc, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	events, unsubscribe := s.Events.Subscribe("")
+	defer unsubscribe()
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", token, CreateNewGameReq{PlayerName: "bob", Bot: "random", TimeControl: &TimeControlReq{PerMoveSeconds: 1}}), &created)
+	var state GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s", created.GameId), token, nil), &state)
+	if state.Clock == nil || state.Clock.PerMoveMs != 1000 || state.Clock.RemainingMs[1] != 1000 {
+		t.Errorf("expect the clocks in the state, got %+v", state.Clock)
+	}
+
+	// nobody moves, the server ends the game on time while the session is read
+	timeout := time.After(3 * time.Second)
+	poll := time.NewTicker(10 * time.Millisecond)
+	defer poll.Stop()
+	for done := false; !done; {
+		select {
+		case e := <-events:
+			done = e.Type == EventTimeout && e.GameId == created.GameId
+		case <-poll.C:
+			decode(t, serve(t, s, http.MethodGet, "/session", token, nil), &GetCurrentSessionResp{})
+		case <-timeout:
+			t.Fatal("expect the game to be lost on time")
+		}
+	}
+	decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s", created.GameId), token, nil), &state)
+	if state.Status != game.StatusWon || state.Winner != 2 || state.Reason != game.EndReasonTimeout {
+		t.Errorf("expect player 1 to lose on time, got %+v", state.GameView)
+	}
+	w := serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/play", created.GameId), token, PlayMoveReq{PlayerId: created.PlayerId, Row: 1, Column: 1})
+	if w.Code == http.StatusOK {
+		t.Errorf("expect moves on a game lost on time to fail, got %d", w.Code)
+	}
+}
+
+func TestResignAndDraw(t *testing.T) {
+	s := NewServer()
+	hostToken := newTestSession(t, s)
+	joinerToken := newTestSession(t, s)
+	newGame := func() (CreateNewGameResp, JoinGameResp) {
+		var created CreateNewGameResp
+		decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &created)
+		var joined JoinGameResp
+		decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), joinerToken, JoinGameReq{PlayerName: "john"}), &joined)
+		return created, joined
+	}
+
+	// the joiner declines a draw and then resigns
+	created, joined := newGame()
+	var offered DrawResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/draw", created.GameId), hostToken, DrawReq{PlayerId: created.PlayerId}), &offered)
+	if offered.DrawOfferedBy != 1 {
+		t.Errorf("expect a draw offer by player 1, got %d", offered.DrawOfferedBy)
+	}
+	if w := serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/draw/accept", created.GameId), hostToken, DrawReq{PlayerId: created.PlayerId}); w.Code != http.StatusConflict {
+		t.Errorf("accepting the own offer returns %d, want %d", w.Code, http.StatusConflict)
+	}
+	var declined DrawResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/draw/decline", created.GameId), joinerToken, DrawReq{PlayerId: joined.PlayerId}), &declined)
+	if declined.DrawOfferedBy != 0 || declined.Status != game.StatusInProgress {
+		t.Errorf("expect the offer to be declined, got %+v", declined.GameView)
+	}
+	var resigned ResignResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/resign", created.GameId), joinerToken, ResignReq{PlayerId: joined.PlayerId}), &resigned)
+	if resigned.Winner != 1 || resigned.Reason != game.EndReasonResign {
+		t.Errorf("expect player 1 to win by resignation, got %+v", resigned.GameView)
+	}
+	var state GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s", created.GameId), hostToken, nil), &state)
+	if state.Reason != game.EndReasonResign {
+		t.Errorf("expect the finished game for the host, got %+v", state.GameView)
+	}
+
+	// the players agree to a draw in a new game
+	created, joined = newGame()
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/draw", created.GameId), joinerToken, DrawReq{PlayerId: joined.PlayerId}), &offered)
+	var accepted DrawResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/draw/accept", created.GameId), hostToken, DrawReq{PlayerId: created.PlayerId}), &accepted)
+	if accepted.Status != game.StatusDraw || accepted.Reason != game.EndReasonAgreement {
+		t.Errorf("expect a draw by agreement, got %+v", accepted.GameView)
+	}
+	var info GetCurrentSessionResp
+	decode(t, serve(t, s, http.MethodGet, "/session", joinerToken, nil), &info)
+	if info.GameId != "" {
+		t.Errorf("expect no active game left, got %q", info.GameId)
+	}
+}
+
+func TestRematch(t *testing.T) {
+	s := NewServer()
+	hostToken := newTestSession(t, s)
+	joinerToken := newTestSession(t, s)
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &created)
+	var joined JoinGameResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), joinerToken, JoinGameReq{PlayerName: "john"}), &joined)
+	rematchTarget := fmt.Sprintf("/games/%s/rematch", created.GameId)
+
+	if w := serve(t, s, http.MethodPost, rematchTarget, hostToken, RematchReq{PlayerId: created.PlayerId}); w.Code != http.StatusConflict {
+		t.Errorf("rematch of a running game returns %d, want %d", w.Code, http.StatusConflict)
+	}
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/resign", created.GameId), hostToken, ResignReq{PlayerId: created.PlayerId}), &ResignResp{})
+
+	var requested RematchResp
+	decode(t, serve(t, s, http.MethodPost, rematchTarget, hostToken, RematchReq{PlayerId: created.PlayerId}), &requested)
+	if requested.RematchRequestedBy != 1 || requested.RematchGameId != "" {
+		t.Errorf("expect a pending rematch request, got %+v", requested.GameView)
+	}
+	var accepted RematchResp
+	decode(t, serve(t, s, http.MethodPost, rematchTarget, joinerToken, RematchReq{PlayerId: joined.PlayerId}), &accepted)
+	if accepted.RematchGameId == "" {
+		t.Fatalf("expect the rematch game id, got %+v", accepted.GameView)
+	}
+
+	// both players follow the rematch from the finished game, the joiner plays X now and moves first
+	var finished GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s", created.GameId), hostToken, nil), &finished)
+	if finished.RematchGameId != accepted.RematchGameId {
+		t.Errorf("expect the rematch id in the finished game, got %q", finished.RematchGameId)
+	}
+	for _, token := range []string{hostToken, joinerToken} {
+		var info GetCurrentSessionResp
+		decode(t, serve(t, s, http.MethodGet, "/session", token, nil), &info)
+		if info.GameId != accepted.RematchGameId {
+			t.Errorf("expect the rematch as active game, got %q", info.GameId)
+		}
+	}
+	var played PlayMoveResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/play", accepted.RematchGameId), joinerToken, PlayMoveReq{PlayerId: joined.PlayerId, Row: 1, Column: 1}), &played)
+	if played.PreviousGameId != created.GameId || *played.Board[1][1] != "X" {
+		t.Errorf("expect X played by the joiner in the linked rematch, got %+v", played.GameView)
+	}
+}
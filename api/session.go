@@ -1,9 +1,13 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -22,53 +26,134 @@ var (
 	GameIdNotMatchErr        = errors.New("game id not match")
 	SessionIdAuthErr         = errors.New("authentication error. invalid session id")
 	NoActiveGameInSessionErr = errors.New("no active game in session")
+	ActiveGameInSessionErr   = errors.New("session has an active game already. please end it before joining another game")
+	OpponentUnavailableErr   = errors.New("your opponent left or is playing another game")
 )
 
 type Session struct {
-	Id         string
+	Id string
+	// mu guards ActiveGame and PlayerId, finished games are cleared from the sessions playing them by other
+	// goroutines, such as the flag timer and the reaper
+	mu         sync.Mutex
 	ActiveGame *game.Game
+	// PlayerId player id of the session in the active game
+	PlayerId string
+	// AccountId and Username of the account the session is signed in to, empty for guests. set before the
+	// session is stored and never changed
+	AccountId string
+	Username  string
+	// lastActivity unix nano time of the last authenticated request, idle sessions are reaped
+	lastActivity atomic.Int64
 }
 
-// CreateGameInSession create an active game in the session and returns the game object
-func (s *Session) CreateGameInSession(playerName string) (*game.Game, error) {
-	gameFactory := game.NewGameFactory{}
-	newGame := gameFactory.CreateGame(playerName)
+// touch records activity on the session at now
+func (s *Session) touch(now time.Time) {
+	s.lastActivity.Store(now.UnixNano())
+}
+
+// LastActivity returns the time of the last authenticated request of the session
+func (s *Session) LastActivity() time.Time {
+	return time.Unix(0, s.lastActivity.Load())
+}
+
+// Game returns the active game of the session, nil if there is none, and the player id of the session in it
+func (s *Session) Game() (*game.Game, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ActiveGame, s.PlayerId
+}
+
+// leave clears the active game of the session if it is the game, returns whether it was
+func (s *Session) leave(g *game.Game) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ActiveGame != g {
+		return false
+	}
+	s.ActiveGame = nil
+	return true
+}
+
+// CreateGameInSession create an active game in the session with the given factory and returns the game object.
+// signed in players play under their username unless they choose another name
+func (s *Session) CreateGameInSession(gameFactory *game.NewGameFactory, playerName string) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createGame(gameFactory, playerName)
+}
+
+// hostGame creates an active game in the session like CreateGameInSession unless the session has an active game
+func (s *Session) hostGame(gameFactory *game.NewGameFactory, playerName string) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ActiveGame != nil {
+		return nil, ActiveGameInSessionErr
+	}
+	return s.createGame(gameFactory, playerName)
+}
+
+// createGame the caller holds the lock
+func (s *Session) createGame(gameFactory *game.NewGameFactory, playerName string) (*game.Game, error) {
+	gameFactory.AccountId = s.AccountId
+	if playerName == "" {
+		playerName = s.Username
+	}
+	newGame, err := gameFactory.CreateGame(playerName)
+	if err != nil {
+		return nil, err
+	}
 	s.ActiveGame = newGame
+	s.PlayerId = newGame.Player1Id
 	return newGame, nil
 }
 
 func (s *Session) GetSessionInfo() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.ActiveGame == nil {
 		return s.Id, ""
 	}
 	return s.Id, s.ActiveGame.Id
 }
 
-func (s *Session) GetGameState(gameId string) (string, error) {
+// GetGameState returns the active game if the game id match
+func (s *Session) GetGameState(gameId string) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.ActiveGame == nil {
-		return "", NoActiveGameInSessionErr
+		return nil, NoActiveGameInSessionErr
 	}
 	if gameId != s.ActiveGame.Id {
-		return "", GameIdNotMatchErr
+		return nil, GameIdNotMatchErr
 	}
-	return s.ActiveGame.ShowGameState(s.Id), nil
+	return s.ActiveGame, nil
 }
 
-// JoinGame join the game and returns a player2 id
-func (s *Session) JoinGame(gameId, playerName string) (string, error) {
-	if s.ActiveGame == nil {
-		return "", NoActiveGameInSessionErr
+// JoinGame join the game, set it as the active game of the session and returns a player2 id
+func (s *Session) JoinGame(g *game.Game, playerName string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the host can not take the second seat of its own game either
+	if s.ActiveGame != nil {
+		return "", ActiveGameInSessionErr
+	}
+	if playerName == "" {
+		playerName = s.Username
 	}
 	player2Id := uuid.NewString()
-	err := s.ActiveGame.Join(gameId, player2Id, playerName)
+	err := g.JoinAsAccount(g.Id, player2Id, playerName, s.AccountId)
 	if err != nil {
 		return "", err
 	}
+	s.ActiveGame = g
+	s.PlayerId = player2Id
 	return player2Id, nil
 }
 
 // EndGame remove the game from active game field in session and return the game pointer
 func (s *Session) EndGame(gameId, playerId string) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.ActiveGame == nil {
 		return nil, NoActiveGameInSessionErr
 	}
@@ -81,17 +166,119 @@ func (s *Session) EndGame(gameId, playerId string) (*game.Game, error) {
 }
 
 // PlayMove play a legal move and returns the game pointer
-func (s *Session) PlayMove(gameId string, playerId string, row int, col int) (*game.Game, error) {
+// row and col are positions inside the sub-board if subBoard is given, positions on the whole board otherwise
+func (s *Session) PlayMove(gameId string, playerId string, subBoard *game.Position, row int, col int) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.ActiveGame == nil {
 		return nil, NoActiveGameInSessionErr
 	}
+	if s.ActiveGame.Id != gameId {
+		return nil, GameIdNotMatchErr
+	}
+	if subBoard != nil {
+		if err := s.ActiveGame.MoveInSubBoard(playerId, *subBoard, row, col); err != nil {
+			return nil, err
+		}
+		return s.ActiveGame, nil
+	}
+	if err := s.ActiveGame.Move(playerId, row, col); err != nil {
+		return nil, err
+	}
+	return s.ActiveGame, nil
+}
+
+// ChooseColor make the opening color choice and returns the game pointer
+func (s *Session) ChooseColor(gameId string, playerId string, choice game.ColorChoice) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.ActiveGame == nil {
 		return nil, NoActiveGameInSessionErr
 	}
 	if s.ActiveGame.Id != gameId {
 		return nil, GameIdNotMatchErr
 	}
-	if err := s.ActiveGame.Move(playerId, row, col); err != nil {
+	if err := s.ActiveGame.ChooseColor(playerId, choice); err != nil {
+		return nil, err
+	}
+	return s.ActiveGame, nil
+}
+
+// RequestTakeback ask the opponent to take back the player's last move and returns the game pointer
+func (s *Session) RequestTakeback(gameId string, playerId string) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ActiveGame == nil {
+		return nil, NoActiveGameInSessionErr
+	}
+	if s.ActiveGame.Id != gameId {
+		return nil, GameIdNotMatchErr
+	}
+	if err := s.ActiveGame.RequestTakeback(playerId); err != nil {
+		return nil, err
+	}
+	return s.ActiveGame, nil
+}
+
+// RespondTakeback accept or decline the opponent's takeback request and returns the game pointer
+func (s *Session) RespondTakeback(gameId string, playerId string, accept bool) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ActiveGame == nil {
+		return nil, NoActiveGameInSessionErr
+	}
+	if s.ActiveGame.Id != gameId {
+		return nil, GameIdNotMatchErr
+	}
+	if err := s.ActiveGame.RespondTakeback(playerId, accept); err != nil {
+		return nil, err
+	}
+	return s.ActiveGame, nil
+}
+
+// Resign resign the game and returns the game pointer
+func (s *Session) Resign(gameId string, playerId string) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ActiveGame == nil {
+		return nil, NoActiveGameInSessionErr
+	}
+	if s.ActiveGame.Id != gameId {
+		return nil, GameIdNotMatchErr
+	}
+	if err := s.ActiveGame.Resign(playerId); err != nil {
+		return nil, err
+	}
+	return s.ActiveGame, nil
+}
+
+// OfferDraw offer the opponent a draw and returns the game pointer
+func (s *Session) OfferDraw(gameId string, playerId string) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ActiveGame == nil {
+		return nil, NoActiveGameInSessionErr
+	}
+	if s.ActiveGame.Id != gameId {
+		return nil, GameIdNotMatchErr
+	}
+	if err := s.ActiveGame.OfferDraw(playerId); err != nil {
+		return nil, err
+	}
+	return s.ActiveGame, nil
+}
+
+// RespondDraw accept or decline the opponent's draw offer and returns the game pointer
+func (s *Session) RespondDraw(gameId string, playerId string, accept bool) (*game.Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ActiveGame == nil {
+		return nil, NoActiveGameInSessionErr
+	}
+	if s.ActiveGame.Id != gameId {
+		return nil, GameIdNotMatchErr
+	}
+	if err := s.ActiveGame.RespondDraw(playerId, accept); err != nil {
 		return nil, err
 	}
 	return s.ActiveGame, nil
@@ -101,11 +288,21 @@ func (s *Session) PlayMove(gameId string, playerId string, row int, col int) (*g
 
 // NewSession create a new session and register into in memory sync map sessions
 func (s *Server) NewSession() (string, error) {
+	return s.newSession(nil)
+}
+
+// newSession create a new session signed in to the account, a guest session if account is nil
+func (s *Server) newSession(account *Account) (string, error) {
 	sid := uuid.NewString()
 	var ss = Session{
 		Id:         sid,
 		ActiveGame: nil,
 	}
+	if account != nil {
+		ss.AccountId = account.Id
+		ss.Username = account.Username
+	}
+	ss.touch(time.Now())
 	// check sessions size. limit 1000
 	var count int
 	s.Sessions.Range(func(k, v any) bool {
@@ -121,84 +318,231 @@ func (s *Server) NewSession() (string, error) {
 
 // DeleteSession delete the session from InMemSession syncMap if id match
 func (s *Server) DeleteSession(sessionId string) error {
-	_, err := s.authenticateSessionId(sessionId)
+	session, err := s.authenticateSessionId(sessionId)
 	if err != nil {
 		return err
 	}
+	// the game of the session goes with it, the opponent sees it abandoned
+	if g, playerId := session.Game(); g != nil {
+		if err := s.abandonGame(sessionId, playerId, g); err != nil {
+			log.Printf("end game %s of deleted session: %v", g.Id, err)
+		}
+		s.OpenGames.Remove(g.Id)
+		s.ActiveGames.Leave(session, g)
+	}
 	s.Sessions.Delete(sessionId)
+	s.ActiveGames.Forget(session)
 	return nil
 }
 
-// GetSessionInfo returns current session id and active/open game id
-func (s *Server) GetSessionInfo(sessionId string) (string, string, error) {
+// RefreshToken issues a new token for an existing session, returns the token and its expiry
+func (s *Server) RefreshToken(sessionId string) (string, time.Time, error) {
+	if _, err := s.authenticateSessionId(sessionId); err != nil {
+		return "", time.Time{}, err
+	}
+	return s.Tokens.Sign(sessionId)
+}
+
+// StreamToken issues a stream token for the session
+func (s *Server) StreamToken(sessionId string) (string, time.Time, error) {
+	if _, err := s.authenticateSessionId(sessionId); err != nil {
+		return "", time.Time{}, err
+	}
+	return s.Tokens.SignStream(sessionId)
+}
+
+// GetSessionInfo returns current session id, active/open game id and the id of the account signed in to
+func (s *Server) GetSessionInfo(sessionId string) (string, string, string, error) {
 	session, err := s.authenticateSessionId(sessionId)
 	if err != nil {
-		return "", "", err
-	}
-	if session.ActiveGame == nil {
-		return session.Id, "", nil
+		return "", "", "", err
 	}
-	return session.Id, session.ActiveGame.Id, nil
+	sessionId, gameId := session.GetSessionInfo()
+	return sessionId, gameId, session.AccountId, nil
 }
 
 // CreateGame create an open game in session, returns game id and player 1 id for the host
-func (s *Server) CreateGame(sessionId string, playerName string) (string, string, error) {
+func (s *Server) CreateGame(sessionId string, playerName string, gameFactory *game.NewGameFactory) (string, string, error) {
 	session, err := s.authenticateSessionId(sessionId)
 	if err != nil {
 		return "", "", err
 	}
-	ga, err := session.CreateGameInSession(playerName)
+	gameFactory.OnFlagFall = s.flagFall
+	replaced, _ := session.Game()
+	ga, err := session.CreateGameInSession(gameFactory, playerName)
 	if err != nil {
 		return "", "", err
 	}
+	// the new game replaces the active game of the session, nobody can join the replaced game any more
+	if replaced != nil {
+		s.OpenGames.Remove(replaced.Id)
+		s.ActiveGames.Leave(session, replaced)
+	}
+	s.ActiveGames.Enter(session, ga, ga.Player1Id)
+	// games against a bot start right away, they are never open
+	if ga.Player2Id == "" {
+		if !ga.Private {
+			s.OpenGames.Add(newOpenGame(ga, s.rating(session), time.Now()))
+		}
+		s.publish(EventOpen, ga)
+	}
 	return ga.Id, ga.Player1Id, nil
 }
 
-// ListOpenGames returns a map of sessionId vs open game id for all sessions
-func (s *Server) ListOpenGames() map[string]string {
-	var openGames = make(map[string]string)
-	s.Sessions.Range(func(sessionId, session any) bool {
-		s := session.(*Session)
-		if s.ActiveGame != nil && s.ActiveGame.Player2Id == "" {
-			openGames[sessionId.(string)] = s.ActiveGame.Id
-		}
-		return true
-	})
-	return openGames
+// ListOpenGames returns a page of the public games waiting for a second player and the cursor of the next page
+func (s *Server) ListOpenGames(filter OpenGameFilter) ([]OpenGame, string, error) {
+	return s.OpenGames.List(filter)
 }
 
-// GetGameState show game state of finished game or active game for the given session id and game id
-func (s *Server) GetGameState(sessionId, gameId string) (string, error) {
+// GetGameState returns the finished game or active game for the given session id and game id
+func (s *Server) GetGameState(sessionId, gameId string) (*game.Game, error) {
 	// check finished game
 	cacheKey := fmt.Sprintf("%s_%s", sessionId, gameId)
 	g, found := s.FinishedGames.Get(cacheKey)
 	if found {
-		ga := g.(*game.Game)
-		return ga.ShowGameState(sessionId), nil
+		return g.(*game.Game), nil
 	}
 	// check active games in session
 	session, err := s.authenticateSessionId(sessionId)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	output, err := session.GetGameState(gameId)
+	ga, err := session.GetGameState(gameId)
+	if errors.Is(err, NoActiveGameInSessionErr) || errors.Is(err, GameIdNotMatchErr) {
+		// other sessions watch public games read-only
+		if g := s.publicGame(gameId); g != nil {
+			return g, nil
+		}
+	}
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return ga, nil
+}
+
+// publicGame returns the active or finished game with the game id unless it is private, nil if there is none
+func (s *Server) publicGame(gameId string) *game.Game {
+	var g *game.Game
+	if ga, err := s.findActiveGame(gameId); err == nil {
+		g = ga
+	} else if ga, found := s.FinishedGames.Get(gameId); found {
+		g = ga.(*game.Game)
 	}
-	return output, nil
+	if g == nil || g.Private {
+		return nil
+	}
+	return g
 }
 
-// JoinGame join a game in a session returns the id for player 2
+// isPlayer checks if the session plays in the game, sessions not playing in it are spectators
+func (s *Server) isPlayer(sessionId string, g *game.Game) bool {
+	ss, ok := s.Sessions.Load(sessionId)
+	if !ok {
+		return false
+	}
+	_, playerId := ss.(*Session).Game()
+	return playerId != "" && (playerId == g.Player1Id || playerId == g.Player2Id)
+}
+
+// watch counts the session as a spectator of the game if it does not play in it, until the returned function is called
+func (s *Server) watch(sessionId string, g *game.Game) func() {
+	if s.isPlayer(sessionId, g) {
+		return func() {}
+	}
+	release := g.Watch()
+	s.publish(EventSpectators, g)
+	return func() {
+		release()
+		s.publish(EventSpectators, g)
+	}
+}
+
+// AnalyzeGame solves the position of a finished or active game visible to the session and returns the game
+// and the outcome of every legal move for the player to move
+func (s *Server) AnalyzeGame(sessionId, gameId string) (*game.Game, []game.MoveAnalysis, error) {
+	g, err := s.GetGameState(sessionId, gameId)
+	if err != nil {
+		return nil, nil, err
+	}
+	analysis, err := g.Analyze()
+	if err != nil {
+		return nil, nil, err
+	}
+	return g, analysis, nil
+}
+
+// GetMoves returns the move history of a finished or active game visible to the session
+func (s *Server) GetMoves(sessionId, gameId string) ([]game.MoveRecord, error) {
+	g, err := s.GetGameState(sessionId, gameId)
+	if err != nil {
+		return nil, err
+	}
+	return g.History(), nil
+}
+
+// ReplayGame returns the game as it was after ply moves, the latest position if ply is nil
+func (s *Server) ReplayGame(sessionId, gameId string, ply *int) (*game.Game, error) {
+	g, err := s.GetGameState(sessionId, gameId)
+	if err != nil {
+		return nil, err
+	}
+	if ply == nil {
+		latest := len(g.History())
+		ply = &latest
+	}
+	return g.Replay(*ply)
+}
+
+// WaitForChange waits until the version of a finished or active game visible to the session moves past since or
+// the timeout elapses, returns the game and whether it changed
+func (s *Server) WaitForChange(ctx context.Context, sessionId, gameId string, since int, timeout time.Duration) (*game.Game, bool, error) {
+	g, err := s.GetGameState(sessionId, gameId)
+	if err != nil {
+		return nil, false, err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-g.Changed(since):
+		return g, true, nil
+	case <-timer.C:
+		return g, false, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// JoinGame join an open game of any session from the given session, returns the id for player 2
 func (s *Server) JoinGame(sessionId, gameId, playerName string) (string, error) {
 	session, err := s.authenticateSessionId(sessionId)
 	if err != nil {
 		return "", err
 	}
-	playerId, err := session.JoinGame(gameId, playerName)
+	g, err := s.findActiveGame(gameId)
+	if err != nil {
+		return "", err
+	}
+	// private games are only joined with their invite code
+	if g.Private {
+		return "", game.GameIdNotfoundErr
+	}
+	return s.seat(session, g, playerName)
+}
+
+// seat joins the session to the game as second player and returns a player2 id
+func (s *Server) seat(session *Session, g *game.Game, playerName string) (string, error) {
+	// two players must not take the last seat at the same time
+	s.joinMu.Lock()
+	defer s.joinMu.Unlock()
+	playerId, err := session.JoinGame(g, playerName)
 	if err != nil {
 		return "", err
 	}
+	s.ActiveGames.Enter(session, g, playerId)
+	s.OpenGames.Remove(g.Id)
+	s.showRatings(g)
+	s.publish(EventJoin, g)
 	return playerId, nil
 }
 
@@ -212,34 +556,255 @@ func (s *Server) EndGame(sessionId, gameId, playerId string) error {
 	if err != nil {
 		return err
 	}
-	cacheKey := fmt.Sprintf("%s_%s", sessionId, gameId)
-	s.FinishedGames.Set(cacheKey, g, 0)
+	s.finishGame(sessionId, g)
+	s.publish(EventEnd, g)
 	return nil
 }
 
-// PlayMove play a legal move and returns the game state
-func (s *Server) PlayMove(sessionId string, gameId string, playerId string, row int, col int) (string, error) {
+// PlayMove play a legal move and returns the game
+func (s *Server) PlayMove(sessionId string, gameId string, playerId string, subBoard *game.Position, row int, col int) (*game.Game, error) {
 	session, err := s.authenticateSessionId(sessionId)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	g, err := session.PlayMove(gameId, playerId, row, col)
+	g, err := session.PlayMove(gameId, playerId, subBoard, row, col)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	// in single player games the bot replies right away
+	if err := g.PlayBotTurn(); err != nil {
+		return nil, err
+	}
+	s.publish(EventMove, g)
+	// if game finished, we need to remove the game from the sessions and add it to finishedGame cache
+	if g.Ended() {
+		s.finishGame(sessionId, g)
+		s.publish(EventEnd, g)
+	}
+	return g, nil
+}
+
+// ChooseColor make the color choice of the swap2 opening and returns the game
+func (s *Server) ChooseColor(sessionId string, gameId string, playerId string, choice game.ColorChoice) (*game.Game, error) {
+	session, err := s.authenticateSessionId(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	g, err := session.ChooseColor(gameId, playerId, choice)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(EventColor, g)
+	return g, nil
+}
+
+// RequestTakeback ask the opponent to take back the player's last move and returns the game
+func (s *Server) RequestTakeback(sessionId string, gameId string, playerId string) (*game.Game, error) {
+	session, err := s.authenticateSessionId(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	g, err := session.RequestTakeback(gameId, playerId)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(EventTakeback, g)
+	return g, nil
+}
+
+// RespondTakeback accept or decline the opponent's takeback request and returns the game
+func (s *Server) RespondTakeback(sessionId string, gameId string, playerId string, accept bool) (*game.Game, error) {
+	session, err := s.authenticateSessionId(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	g, err := session.RespondTakeback(gameId, playerId, accept)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(EventTakeback, g)
+	return g, nil
+}
+
+// Resign resign the game, the opponent wins, and returns the game
+func (s *Server) Resign(sessionId string, gameId string, playerId string) (*game.Game, error) {
+	session, err := s.authenticateSessionId(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	g, err := session.Resign(gameId, playerId)
+	if err != nil {
+		return nil, err
+	}
+	s.finishGame(sessionId, g)
+	s.publish(EventEnd, g)
+	return g, nil
+}
+
+// OfferDraw offer the opponent a draw, or accept the opponent's pending offer, and returns the game
+func (s *Server) OfferDraw(sessionId string, gameId string, playerId string) (*game.Game, error) {
+	session, err := s.authenticateSessionId(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	g, err := session.OfferDraw(gameId, playerId)
+	if err != nil {
+		return nil, err
+	}
+	s.publishDraw(sessionId, g)
+	return g, nil
+}
+
+// RespondDraw accept or decline the opponent's draw offer and returns the game
+func (s *Server) RespondDraw(sessionId string, gameId string, playerId string, accept bool) (*game.Game, error) {
+	session, err := s.authenticateSessionId(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	g, err := session.RespondDraw(gameId, playerId, accept)
+	if err != nil {
+		return nil, err
+	}
+	s.publishDraw(sessionId, g)
+	return g, nil
+}
+
+// publishDraw publishes the draw offer or answer, finishing the game if the draw was agreed
+func (s *Server) publishDraw(sessionId string, g *game.Game) {
+	if g.View().Status == game.StatusDraw {
+		s.finishGame(sessionId, g)
+		s.publish(EventEnd, g)
+		return
+	}
+	s.publish(EventDraw, g)
+}
+
+// Rematch ask for a rematch of the finished game and returns it. once both players asked, the rematch with X and O
+// swapped becomes the active game of both sessions and its id is set in the finished game
+func (s *Server) Rematch(sessionId string, gameId string, playerId string) (*game.Game, error) {
+	session, err := s.authenticateSessionId(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	g, err := s.GetGameState(sessionId, gameId)
+	if err != nil {
+		return nil, err
 	}
-	// if game finished, we need to remove the game from session and add it to finishedGame cache
-	if g.State.End {
-		session.ActiveGame = nil
+	s.joinMu.Lock()
+	defer s.joinMu.Unlock()
+	var opponent *Session
+	var opponentId string
+	if g.Bot == nil && (playerId == g.Player1Id || playerId == g.Player2Id) {
+		opponentId = g.Player1Id
+		if playerId == g.Player1Id {
+			opponentId = g.Player2Id
+		}
+		opponent = s.sessionOfPlayer(opponentId)
+		if opponent == nil || opponent == session {
+			return nil, OpponentUnavailableErr
+		}
+	}
+	r, err := rematchInSessions(g, session, playerId, opponent, opponentId)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil {
+		s.ActiveGames.Enter(session, r, playerId)
+		if opponent != nil {
+			s.ActiveGames.Enter(opponent, r, opponentId)
+		}
+		s.showRatings(r)
+		// the bot starts right away if it plays X now
+		if err := r.PlayBotTurn(); err != nil {
+			return nil, err
+		}
+	}
+	s.publish(EventRematch, g)
+	return g, nil
+}
+
+// rematchInSessions asks for the rematch of the finished game and seats both sessions in the rematch once it is
+// created. both sessions stay locked from checking they are free until they are seated, so the rematch is never
+// created while one of them starts another game. opponent is nil in games against a bot
+func rematchInSessions(g *game.Game, session *Session, playerId string, opponent *Session, opponentId string) (*game.Game, error) {
+	// sessions are locked in id order, two rematches locking the same sessions never wait for each other
+	locked := []*Session{session}
+	if opponent != nil {
+		locked = append(locked, opponent)
+		if opponent.Id < session.Id {
+			locked[0], locked[1] = opponent, session
+		}
+	}
+	for _, ss := range locked {
+		ss.mu.Lock()
+		defer ss.mu.Unlock()
+	}
+	// finished games are no active games anymore
+	if session.ActiveGame == g {
+		return nil, game.GameNotFinishedErr
+	} else if session.ActiveGame != nil {
+		return nil, ActiveGameInSessionErr
+	}
+	if opponent != nil && opponent.ActiveGame != nil {
+		return nil, OpponentUnavailableErr
+	}
+	r, err := g.RequestRematch(playerId)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	session.ActiveGame, session.PlayerId = r, playerId
+	if opponent != nil {
+		opponent.ActiveGame, opponent.PlayerId = r, opponentId
+	}
+	return r, nil
+}
+
+// sessionOfPlayer returns the session of the player id, nil if the player has no session anymore
+func (s *Server) sessionOfPlayer(playerId string) *Session {
+	return s.ActiveGames.Session(playerId)
+}
+
+// findActiveGame returns the active game with the game id from any session
+func (s *Server) findActiveGame(gameId string) (*game.Game, error) {
+	g, found := s.ActiveGames.Get(gameId)
+	if !found {
+		return nil, game.GameIdNotfoundErr
+	}
+	return g, nil
+}
+
+// finishGame removes the finished game from every session playing it and adds it to the finished game cache
+// for each of them, so both players can see the result
+func (s *Server) finishGame(sessionId string, g *game.Game) {
+	var sessionIds []string
+	if sessionId != "" {
+		sessionIds = append(sessionIds, sessionId)
+	}
+	for _, session := range s.ActiveGames.Remove(g) {
+		if session.leave(g) && session.Id != sessionId {
+			sessionIds = append(sessionIds, session.Id)
+		}
+	}
+	if s.FinishedGames.ItemCount() > DefaultSize {
+		s.FinishedGames.DeleteExpired()
 		if s.FinishedGames.ItemCount() > DefaultSize {
-			s.FinishedGames.DeleteExpired()
-			if s.FinishedGames.ItemCount() > DefaultSize {
-				log.Print("warning.finishedGames cache max size reached")
-			}
+			log.Print("warning.finishedGames cache max size reached")
 		}
-		cacheKey := fmt.Sprintf("%s_%s", sessionId, gameId)
+	}
+	for _, id := range sessionIds {
+		cacheKey := fmt.Sprintf("%s_%s", id, g.Id)
 		s.FinishedGames.Set(cacheKey, g, 0)
 	}
-	return g.ShowGameState(sessionId), nil
+	// spectators find finished games by their id
+	s.FinishedGames.Set(g.Id, g, 0)
+	s.OpenGames.Remove(g.Id)
+	s.Ratings.Record(g)
+}
+
+// flagFall moves a game lost on time to the finished games of its players
+func (s *Server) flagFall(g *game.Game) {
+	s.finishGame("", g)
+	s.publish(EventTimeout, g)
 }
 
 func (s *Server) authenticateSessionId(sessionId string) (*Session, error) {
@@ -251,5 +816,6 @@ func (s *Server) authenticateSessionId(sessionId string) (*Session, error) {
 	if session == nil {
 		return nil, SessionIdAuthErr
 	}
+	session.touch(time.Now())
 	return session, nil
 }
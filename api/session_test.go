@@ -1,6 +1,8 @@
 package api
 
 import (
+	"errors"
+
 	"github.com/minozihao/tic-tac-toe-server/game"
 	"testing"
 )
@@ -41,7 +43,7 @@ func TestSession_CreateGameInSession(t *testing.T) {
 				Id:         tt.fields.Id,
 				ActiveGame: tt.fields.ActiveGame,
 			}
-			got, err := s.CreateGameInSession(tt.args.playerName)
+			got, err := s.CreateGameInSession(&game.NewGameFactory{}, tt.args.playerName)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateGameInSession() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -94,32 +96,61 @@ func TestSession_GetSessionInfo(t *testing.T) {
 }
 
 func TestSession_JoinGame(t *testing.T) {
+	newGame := func() *game.Game {
+		g, err := (&game.NewGameFactory{}).CreateGame("bob")
+		if err != nil {
+			t.Fatalf("CreateGame() unexpected error %v", err)
+		}
+		return g
+	}
+	openGame := newGame()
 	type fields struct {
 		Id         string
 		ActiveGame *game.Game
 	}
 	type args struct {
-		gameId     string
+		game       *game.Game
 		playerName string
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr bool
+		name        string
+		fields      fields
+		args        args
+		wantErrType error
 	}{
 		{
 			name: "general success case",
 			fields: fields{
 				Id: "test-session-id",
-				ActiveGame: &game.Game{
-					Id: "test_game_id",
-				},
 			},
 			args: args{
-				gameId:     "test_game_id",
+				game:       newGame(),
+				playerName: "john",
+			},
+		},
+		{
+			name: "join the game of the own session",
+			fields: fields{
+				Id:         "test-session-id",
+				ActiveGame: openGame,
+			},
+			args: args{
+				game:       openGame,
+				playerName: "john",
+			},
+			wantErrType: ActiveGameInSessionErr,
+		},
+		{
+			name: "ActiveGameInSessionErr",
+			fields: fields{
+				Id:         "test-session-id",
+				ActiveGame: &game.Game{Id: "another_game_id"},
+			},
+			args: args{
+				game:       newGame(),
 				playerName: "john",
 			},
+			wantErrType: ActiveGameInSessionErr,
 		},
 	}
 	for _, tt := range tests {
@@ -128,14 +159,72 @@ func TestSession_JoinGame(t *testing.T) {
 				Id:         tt.fields.Id,
 				ActiveGame: tt.fields.ActiveGame,
 			}
-			got, err := s.JoinGame(tt.args.gameId, tt.args.playerName)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("JoinGame() error = %v, wantErr %v", err, tt.wantErr)
+			got, err := s.JoinGame(tt.args.game, tt.args.playerName)
+			if !errors.Is(err, tt.wantErrType) {
+				t.Errorf("JoinGame() error = %v, wantErr %v", err, tt.wantErrType)
+				return
+			}
+			if err != nil {
 				return
 			}
 			if got == "" {
 				t.Error("JoinGame() expect returns a generated uuid player id for player")
 			}
+			if s.ActiveGame != tt.args.game {
+				t.Error("JoinGame() expect the joined game to be the active game of the session")
+			}
+		})
+	}
+}
+
+func TestRematchInSessions(t *testing.T) {
+	newFinishedGame := func() *game.Game {
+		g, err := (&game.NewGameFactory{}).CreateGame("bob")
+		if err != nil {
+			t.Fatalf("CreateGame() unexpected error %v", err)
+		}
+		if err := g.Join(g.Id, "player2", "john"); err != nil {
+			t.Fatalf("Join() unexpected error %v", err)
+		}
+		if err := g.Resign(g.Player1Id); err != nil {
+			t.Fatalf("Resign() unexpected error %v", err)
+		}
+		return g
+	}
+	busy := &game.Game{Id: "another_game_id"}
+	tests := []struct {
+		name         string
+		sessionGame  *game.Game
+		opponentGame *game.Game
+		wantErrType  error
+	}{
+		{name: "both sessions free"},
+		{name: "session busy", sessionGame: busy, wantErrType: ActiveGameInSessionErr},
+		{name: "opponent busy", opponentGame: busy, wantErrType: OpponentUnavailableErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newFinishedGame()
+			session := &Session{Id: "a", ActiveGame: tt.sessionGame}
+			opponent := &Session{Id: "b", ActiveGame: tt.opponentGame}
+			// the opponent asked first, the second request creates the rematch
+			if _, err := g.RequestRematch(g.Player2Id); err != nil {
+				t.Fatalf("RequestRematch() unexpected error %v", err)
+			}
+			r, err := rematchInSessions(g, session, g.Player1Id, opponent, g.Player2Id)
+			if !errors.Is(err, tt.wantErrType) {
+				t.Fatalf("rematchInSessions() error = %v, wantErr %v", err, tt.wantErrType)
+			}
+			if err != nil {
+				// no rematch is created or linked while a session is busy
+				if v := g.View(); v.RematchGameId != "" || session.ActiveGame != tt.sessionGame || opponent.ActiveGame != tt.opponentGame {
+					t.Errorf("expect nothing to change, got rematch %q", v.RematchGameId)
+				}
+				return
+			}
+			if r == nil || session.ActiveGame != r || opponent.ActiveGame != r || session.PlayerId != g.Player1Id || opponent.PlayerId != g.Player2Id {
+				t.Errorf("expect both sessions seated in the rematch, got %v and %v", session.ActiveGame, opponent.ActiveGame)
+			}
 		})
 	}
 }
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// sseHeartbeat comment sent on idle streams so proxies keep them open
+const sseHeartbeat = 30 * time.Second
+
+// lobbyEvents event types streamed to clients following the lobby, games opened, filled and finished
+var lobbyEvents = map[EventType]bool{
+	EventOpen:    true,
+	EventJoin:    true,
+	EventEnd:     true,
+	EventTimeout: true,
+}
+
+// streamEvents stream server-sent events. lobby events of all public games, or every event of the game given by the
+// gameId query parameter, sessions not playing in it watch it as spectators. new clients get the events from now on,
+// reconnecting clients get the events after the Last-Event-ID header (or lastEventId query parameter) replayed from
+// the event log. clients falling behind are disconnected and resume the same way. the token may be given as the token
+// query parameter for event sources
+func (s *Server) streamEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateStream(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		// new clients start at the latest event, it is read before subscribing so no event falls in between
+		lastId := s.Events.LastId()
+		if v := r.Header.Get("Last-Event-ID"); v != "" || r.URL.Query().Get("lastEventId") != "" {
+			if v == "" {
+				v = r.URL.Query().Get("lastEventId")
+			}
+			lastId, err = strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Errorf("invalid last event id, %w", err).Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, errors.New("streaming not supported").Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// subscribe before reading the log so no event falls in between
+		gameId := r.URL.Query().Get("gameId")
+		events, unsubscribe := s.Events.Subscribe(gameId)
+		defer unsubscribe()
+		if gameId != "" {
+			g, err := s.GetGameState(sessionId, gameId)
+			if errors.Is(err, SessionIdAuthErr) {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			} else if errors.Is(err, GameIdNotMatchErr) || errors.Is(err, NoActiveGameInSessionErr) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			defer s.watch(sessionId, g)()
+		}
+		// private games stay out of the lobby
+		wanted := func(e Event) bool {
+			return e.Id > lastId && (gameId != "" || (lobbyEvents[e.Type] && !e.State.Private))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		for _, e := range s.Events.Since(lastId, gameId) {
+			if !wanted(e) {
+				continue
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+			lastId = e.Id
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(sseHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case e, ok := <-events:
+				// the client fell behind, it reconnects with the id of the last event it got
+				if !ok {
+					return
+				}
+				// events already replayed from the log are skipped
+				if !wanted(e) {
+					continue
+				}
+				if err := writeSSE(w, e); err != nil {
+					return
+				}
+				lastId = e.Id
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			case <-s.stop:
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSE writes the event in the server-sent events format
+func writeSSE(w http.ResponseWriter, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, data)
+	return err
+}
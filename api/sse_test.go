@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openEventStream connects to the event stream and returns a function reading the next event
+func openEventStream(t *testing.T, url string, token string, lastEventId string) func() Event {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(res.Body)
+	return func() Event {
+		var id, eventType string
+		var e Event
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && id != "":
+				if fmt.Sprint(e.Id) != id || string(e.Type) != eventType {
+					t.Errorf("id %s and event %s do not match the data %+v", id, eventType, e)
+				}
+				return e
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				eventType = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	// cleanups run after the event streams are closed by theirs
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	hostToken := newTestSession(t, s)
+	joinerToken := newTestSession(t, s)
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &created)
+
+	// the lobby stream replays the open game from the log and follows the game being filled
+	lobby := openEventStream(t, ts.URL+"/events", joinerToken, "0")
+	if e := lobby(); e.Type != EventOpen || e.GameId != created.GameId {
+		t.Errorf("got %s event of %s, want the open event", e.Type, e.GameId)
+	}
+	var joined JoinGameResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), joinerToken, JoinGameReq{PlayerName: "john"}), &joined)
+	joinEvent := lobby()
+	if joinEvent.Type != EventJoin {
+		t.Errorf("got %s event, want the join event", joinEvent.Type)
+	}
+
+	// moves are only streamed to followers of the game, the lobby sees the end
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/play", created.GameId), hostToken, PlayMoveReq{PlayerId: created.PlayerId, Row: 1, Column: 1}), &PlayMoveResp{})
+	serve(t, s, http.MethodDelete, fmt.Sprintf("/games/%s", created.GameId), hostToken, EndGameReq{PlayerId: created.PlayerId})
+	if e := lobby(); e.Type != EventEnd {
+		t.Errorf("got %s event, want the end event", e.Type)
+	}
+
+	// a client reconnecting to the game stream after the join event catches up on the move and the end
+	game := openEventStream(t, fmt.Sprintf("%s/events?gameId=%s", ts.URL, created.GameId), joinerToken, fmt.Sprint(joinEvent.Id))
+	if e := game(); e.Type != EventMove || e.State.Board[1][1] == nil {
+		t.Errorf("got %s event, want the move event", e.Type)
+	}
+	if e := game(); e.Type != EventEnd {
+		t.Errorf("got %s event, want the end event", e.Type)
+	}
+
+	// a new client gets no events from before it connected
+	fresh := openEventStream(t, ts.URL+"/events", joinerToken, "")
+	var next CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &next)
+	if e := fresh(); e.Type != EventOpen || e.GameId != next.GameId {
+		t.Errorf("got %s event of %s, want the open event of the new game", e.Type, e.GameId)
+	}
+
+	// only players can follow a private game
+	var private CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", newTestSession(t, s), CreateNewGameReq{PlayerName: "bob", Private: true}), &private)
+	w := serve(t, s, http.MethodGet, fmt.Sprintf("/events?gameId=%s", private.GameId), newTestSession(t, s), nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expect status %d for a private game of another session, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestSpectators(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	hostToken := newTestSession(t, s)
+	spectatorToken := newTestSession(t, s)
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob", Bot: "random"}), &created)
+	var private CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", newTestSession(t, s), CreateNewGameReq{PlayerName: "john", Private: true}), &private)
+
+	// any session watches a public game read-only
+	openEventStream(t, fmt.Sprintf("%s/events?gameId=%s", ts.URL, created.GameId), spectatorToken, "")
+	var state GetGameStateResp
+	decode(t, serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s", created.GameId), spectatorToken, nil), &state)
+	if state.Spectators != 1 {
+		t.Errorf("expect 1 spectator, got %d", state.Spectators)
+	}
+	if w := serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/play", created.GameId), spectatorToken, PlayMoveReq{PlayerId: created.PlayerId, Row: 1, Column: 1}); w.Code == http.StatusOK {
+		t.Error("expect spectators not to play moves")
+	}
+
+	// private games are hidden from spectators and from the open games list
+	if w := serve(t, s, http.MethodGet, fmt.Sprintf("/games/%s", private.GameId), spectatorToken, nil); w.Code == http.StatusOK {
+		t.Errorf("expect a private game to be hidden, got %d", w.Code)
+	}
+	var open ListOpenGamesResp
+	decode(t, serve(t, s, http.MethodGet, "/games", spectatorToken, nil), &open)
+	for _, g := range open.Games {
+		if g.GameId == private.GameId {
+			t.Error("expect the private game not to be listed")
+		}
+	}
+}
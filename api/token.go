@@ -0,0 +1,170 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTokenTTL how long a session token is valid before it has to be refreshed
+const DefaultTokenTTL = 24 * time.Hour
+
+// DefaultStreamTokenTTL how long a stream token can be used to open a websocket or an event stream
+const DefaultStreamTokenTTL = time.Minute
+
+const bearerPrefix = "Bearer "
+
+// streamPurpose purpose of the tokens that are only accepted as the token query parameter of streams
+const streamPurpose = "stream"
+
+// predefined errors, all of them are answered with 401
+
+var (
+	MissingTokenErr   = errors.New("authentication error. no bearer token found in header authorization. create a session with POST /session and send Authorization: Bearer <token>")
+	MalformedTokenErr = errors.New("authentication error. malformed bearer token")
+	TamperedTokenErr  = errors.New("authentication error. bearer token signature does not match")
+	ExpiredTokenErr   = errors.New("authentication error. bearer token expired. refresh it before it expires or create a new session")
+	StreamTokenErr    = errors.New("authentication error. stream tokens only open websockets and event streams")
+	SessionTokenErr   = errors.New("authentication error. the token query parameter only takes stream tokens. get one with POST /session/stream-token")
+)
+
+// tokenClaims payload of a session token
+type tokenClaims struct {
+	SessionId string `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	// Purpose empty for session tokens
+	Purpose string `json:"pur,omitempty"`
+}
+
+// TokenSigner issues and verifies HMAC-SHA256 signed session tokens of the form
+// base64url(claims) + "." + base64url(signature)
+type TokenSigner struct {
+	key []byte
+	ttl time.Duration
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// NewTokenSigner returns a signer using the key, a random key is generated if it is empty
+// so tokens do not outlive the process that issued them
+func NewTokenSigner(key []byte, ttl time.Duration) *TokenSigner {
+	if len(key) == 0 {
+		key = make([]byte, sha256.Size)
+		if _, err := rand.Read(key); err != nil {
+			panic(fmt.Sprintf("api: generate token signing key: %v", err))
+		}
+	}
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	return &TokenSigner{key: key, ttl: ttl, now: time.Now}
+}
+
+// Sign issues a token for the session id and returns it with its expiry
+func (ts *TokenSigner) Sign(sessionId string) (string, time.Time, error) {
+	return ts.sign(sessionId, "", ts.ttl)
+}
+
+// SignStream issues a short-lived token for the session id that only opens websockets and event streams. it is
+// passed in the URL, where it may end up in access logs, so it is useless for anything else and soon expires
+func (ts *TokenSigner) SignStream(sessionId string) (string, time.Time, error) {
+	return ts.sign(sessionId, streamPurpose, DefaultStreamTokenTTL)
+}
+
+func (ts *TokenSigner) sign(sessionId string, purpose string, ttl time.Duration) (string, time.Time, error) {
+	now := ts.now()
+	expiresAt := now.Add(ttl)
+	payload, err := json.Marshal(tokenClaims{
+		SessionId: sessionId,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		Purpose:   purpose,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(ts.signature(encoded)), time.Unix(expiresAt.Unix(), 0), nil
+}
+
+// Verify checks the signature and expiry of the session token and returns the session id it was issued for
+func (ts *TokenSigner) Verify(token string) (string, error) {
+	claims, err := ts.verify(token)
+	if err != nil {
+		return "", err
+	}
+	if claims.Purpose != "" {
+		return "", StreamTokenErr
+	}
+	return claims.SessionId, nil
+}
+
+// VerifyStream checks the signature and expiry of the stream token and returns the session id it was issued for
+func (ts *TokenSigner) VerifyStream(token string) (string, error) {
+	claims, err := ts.verify(token)
+	if err != nil {
+		return "", err
+	}
+	if claims.Purpose != streamPurpose {
+		return "", SessionTokenErr
+	}
+	return claims.SessionId, nil
+}
+
+func (ts *TokenSigner) verify(token string) (*tokenClaims, error) {
+	encoded, sig, found := strings.Cut(token, ".")
+	if !found {
+		return nil, MalformedTokenErr
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, MalformedTokenErr
+	}
+	if !hmac.Equal(signature, ts.signature(encoded)) {
+		return nil, TamperedTokenErr
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, MalformedTokenErr
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.SessionId == "" {
+		return nil, MalformedTokenErr
+	}
+	if !ts.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, ExpiredTokenErr
+	}
+	return &claims, nil
+}
+
+func (ts *TokenSigner) signature(encoded string) []byte {
+	mac := hmac.New(sha256.New, ts.key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// authenticateRequest verifies the bearer token in the Authorization header and returns its session id
+func (s *Server) authenticateRequest(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return "", MissingTokenErr
+	}
+	return s.Tokens.Verify(strings.TrimSpace(strings.TrimPrefix(header, bearerPrefix)))
+}
+
+// authenticateStream verifies the bearer token of the Authorization header, or the stream token of the token query
+// parameter if the header is not set. browsers can not set headers on websockets and event sources
+func (s *Server) authenticateStream(r *http.Request) (string, error) {
+	if token := r.URL.Query().Get("token"); token != "" && r.Header.Get("Authorization") == "" {
+		return s.Tokens.VerifyStream(token)
+	}
+	return s.authenticateRequest(r)
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenSigner_Verify(t *testing.T) {
+	signer := NewTokenSigner([]byte("test-key"), time.Hour)
+	token, expiresAt, err := signer.Sign("test_session_id")
+	if err != nil {
+		t.Fatalf("Sign() unexpected error %v", err)
+	}
+	if expiresAt.Before(time.Now().Add(59 * time.Minute)) {
+		t.Errorf("Sign() expires at %v, want in an hour", expiresAt)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+	otherToken, _, err := NewTokenSigner([]byte("other-key"), time.Hour).Sign("test_session_id")
+	if err != nil {
+		t.Fatalf("Sign() unexpected error %v", err)
+	}
+	expired := NewTokenSigner([]byte("test-key"), time.Hour)
+	expired.now = func() time.Time { return time.Now().Add(-2 * time.Hour) }
+	expiredToken, _, err := expired.Sign("test_session_id")
+	if err != nil {
+		t.Fatalf("Sign() unexpected error %v", err)
+	}
+	streamToken, _, err := signer.SignStream("test_session_id")
+	if err != nil {
+		t.Fatalf("SignStream() unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		wantSessionId string
+		wantErrType   error
+	}{
+		{
+			name:          "valid token",
+			token:         token,
+			wantSessionId: "test_session_id",
+		},
+		{
+			name:        "TamperedTokenErr payload",
+			token:       strings.ToUpper(payload[:1]) + payload[1:] + "." + signature,
+			wantErrType: TamperedTokenErr,
+		},
+		{
+			name:        "TamperedTokenErr signing key",
+			token:       otherToken,
+			wantErrType: TamperedTokenErr,
+		},
+		{
+			name:        "ExpiredTokenErr",
+			token:       expiredToken,
+			wantErrType: ExpiredTokenErr,
+		},
+		{
+			name:        "StreamTokenErr",
+			token:       streamToken,
+			wantErrType: StreamTokenErr,
+		},
+		{
+			name:        "MalformedTokenErr raw session id",
+			token:       "test_session_id",
+			wantErrType: MalformedTokenErr,
+		},
+		{
+			name:        "MalformedTokenErr signature encoding",
+			token:       payload + ".!!",
+			wantErrType: MalformedTokenErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := signer.Verify(tt.token)
+			if !errors.Is(err, tt.wantErrType) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErrType)
+			}
+			if got != tt.wantSessionId {
+				t.Errorf("Verify() = %q, want %q", got, tt.wantSessionId)
+			}
+		})
+	}
+}
+
+func TestTokenSigner_VerifyStream(t *testing.T) {
+	signer := NewTokenSigner([]byte("test-key"), time.Hour)
+	sessionToken, _, err := signer.Sign("test_session_id")
+	if err != nil {
+		t.Fatalf("Sign() unexpected error %v", err)
+	}
+	streamToken, expiresAt, err := signer.SignStream("test_session_id")
+	if err != nil {
+		t.Fatalf("SignStream() unexpected error %v", err)
+	}
+	if expiresAt.After(time.Now().Add(DefaultStreamTokenTTL)) {
+		t.Errorf("SignStream() expires at %v, want within %v", expiresAt, DefaultStreamTokenTTL)
+	}
+	expired := NewTokenSigner([]byte("test-key"), time.Hour)
+	expired.now = func() time.Time { return time.Now().Add(-2 * DefaultStreamTokenTTL) }
+	expiredToken, _, err := expired.SignStream("test_session_id")
+	if err != nil {
+		t.Fatalf("SignStream() unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		wantSessionId string
+		wantErrType   error
+	}{
+		{
+			name:          "valid token",
+			token:         streamToken,
+			wantSessionId: "test_session_id",
+		},
+		{
+			name:        "SessionTokenErr",
+			token:       sessionToken,
+			wantErrType: SessionTokenErr,
+		},
+		{
+			name:        "ExpiredTokenErr",
+			token:       expiredToken,
+			wantErrType: ExpiredTokenErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := signer.VerifyStream(tt.token)
+			if !errors.Is(err, tt.wantErrType) {
+				t.Errorf("VerifyStream() error = %v, wantErr %v", err, tt.wantErrType)
+			}
+			if got != tt.wantSessionId {
+				t.Errorf("VerifyStream() = %q, want %q", got, tt.wantSessionId)
+			}
+		})
+	}
+}
+
+func TestRefreshSession(t *testing.T) {
+	s := NewServer(WithTokenSigningKey([]byte("test-key")), WithTokenTTL(time.Minute))
+	token := newTestSession(t, s)
+
+	var refreshed RefreshSessionResp
+	decode(t, serve(t, s, http.MethodPost, "/session/refresh", token, nil), &refreshed)
+	if refreshed.Token == "" || refreshed.ExpiresAt.After(time.Now().Add(time.Minute)) {
+		t.Errorf("unexpected refreshed token %+v", refreshed)
+	}
+	var info GetCurrentSessionResp
+	decode(t, serve(t, s, http.MethodGet, "/session", refreshed.Token, nil), &info)
+	if info.SessionId != refreshed.SessionId {
+		t.Errorf("refreshed token authenticates session %q, want %q", info.SessionId, refreshed.SessionId)
+	}
+
+	// a server with the same key accepts the token, one with another key does not
+	if _, err := NewServer(WithTokenSigningKey([]byte("test-key"))).Tokens.Verify(token); err != nil {
+		t.Errorf("Verify() unexpected error %v with the shared key", err)
+	}
+	if _, err := NewServer().Tokens.Verify(token); !errors.Is(err, TamperedTokenErr) {
+		t.Errorf("Verify() error = %v, want %v with a random key", err, TamperedTokenErr)
+	}
+
+	// a deleted session can not be refreshed
+	serve(t, s, http.MethodDelete, "/session", token, nil)
+	w := serve(t, s, http.MethodPost, "/session/refresh", token, nil)
+	if w.Code != http.StatusUnauthorized || w.Body.String() != SessionIdAuthErr.Error()+"\n" {
+		t.Errorf("refresh of a deleted session returns %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthenticateRequest_Unauthorized(t *testing.T) {
+	s := NewServer()
+	streamToken, _, err := s.Tokens.SignStream("test_session_id")
+	if err != nil {
+		t.Fatalf("SignStream() unexpected error %v", err)
+	}
+	tests := []struct {
+		name   string
+		header string
+		want   error
+	}{
+		{
+			name: "MissingTokenErr no header",
+			want: MissingTokenErr,
+		},
+		{
+			name:   "MissingTokenErr raw session id",
+			header: "test_session_id",
+			want:   MissingTokenErr,
+		},
+		{
+			name:   "MalformedTokenErr",
+			header: "Bearer test_session_id",
+			want:   MalformedTokenErr,
+		},
+		{
+			name:   "StreamTokenErr",
+			header: "Bearer " + streamToken,
+			want:   StreamTokenErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/session", nil)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized || w.Body.String() != tt.want.Error()+"\n" {
+				t.Errorf("got %d %q, want 401 %q", w.Code, w.Body.String(), tt.want.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// websocket timings, clients answering pings keep the connection open
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPingPeriod = 30 * time.Second
+	wsPongWait   = wsPingPeriod * 2
+)
+
+// EventState snapshot of the game sent when a websocket connects
+const EventState EventType = "state"
+
+// wsMoveMessage type of the websocket message playing a move
+const wsMoveMessage = "move"
+
+// UnknownWsMessageErr predefined error for websocket messages other than moves
+var UnknownWsMessageErr = fmt.Errorf("unknown message type. supported: %q", wsMoveMessage)
+
+// gameWebSocket push the state of the game on every change and play moves sent over the socket, spectators only
+// receive the changes.
+// browsers can not set the Authorization header on websockets, so the token may be given as the token query parameter
+func (s *Server) gameWebSocket() http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionId, err := s.authenticateStream(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		gameId, found := mux.Vars(r)["gameId"]
+		if !found {
+			http.Error(w, errors.New("game id not found in path").Error(), http.StatusBadRequest)
+			return
+		}
+
+		// subscribe before the snapshot is taken so no change is missed
+		events, unsubscribe := s.Events.Subscribe(gameId)
+		defer unsubscribe()
+		g, err := s.GetGameState(sessionId, gameId)
+		if errors.Is(err, SessionIdAuthErr) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, GameIdNotMatchErr) || errors.Is(err, NoActiveGameInSessionErr) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader already answered the request
+			return
+		}
+		defer conn.Close()
+		defer s.watch(sessionId, g)()
+		if err := s.writeWs(conn, Event{Type: EventState, GameId: g.Id, Time: time.Now(), State: g.View()}); err != nil {
+			return
+		}
+
+		stop := make(chan struct{})
+		defer close(stop)
+		errs, closed := s.readWs(conn, sessionId, gameId, stop)
+		ping := time.NewTicker(wsPingPeriod)
+		defer ping.Stop()
+		for {
+			select {
+			case e, ok := <-events:
+				// the client fell behind, it reconnects for a new snapshot
+				if !ok {
+					conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "fell behind the game events, please reconnect"), time.Now().Add(wsWriteWait))
+					return
+				}
+				if err := s.writeWs(conn, e); err != nil {
+					return
+				}
+			case err := <-errs:
+				if err := s.writeWs(conn, WsErrorResp{Type: "error", Error: err.Error()}); err != nil {
+					return
+				}
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}
+}
+
+// readWs plays the moves read from the connection until it is closed. errors of the moves are sent to the returned
+// channel, the second channel is closed once the connection is gone
+func (s *Server) readWs(conn *websocket.Conn, sessionId string, gameId string, stop <-chan struct{}) (<-chan error, <-chan struct{}) {
+	errs := make(chan error)
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg WsPlayMoveReq
+			if err = json.Unmarshal(data, &msg); err != nil {
+				err = fmt.Errorf("invalid message, %w", err)
+			} else if msg.Type != wsMoveMessage {
+				err = UnknownWsMessageErr
+			} else {
+				// the new state reaches the client through the event bus
+				_, err = s.PlayMove(sessionId, gameId, msg.PlayerId, msg.SubBoard, msg.Row, msg.Column)
+			}
+			if err == nil {
+				continue
+			}
+			select {
+			case errs <- err:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return errs, closed
+}
+
+func (s *Server) writeWs(conn *websocket.Conn, v any) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(v)
+}
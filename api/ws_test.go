@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/minozihao/tic-tac-toe-server/game"
+)
+
+func TestGameWebSocket(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	hostToken := newTestSession(t, s)
+	joinerToken := newTestSession(t, s)
+	var created CreateNewGameResp
+	decode(t, serve(t, s, http.MethodPost, "/games", hostToken, CreateNewGameReq{PlayerName: "bob"}), &created)
+
+	wsURL := fmt.Sprintf("%s/games/%s/ws", strings.Replace(ts.URL, "http", "ws", 1), created.GameId)
+	if _, res, err := websocket.DefaultDialer.Dial(wsURL, nil); err == nil || res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expect unauthenticated websockets to be refused, got %v", err)
+	}
+	// the session token is not accepted in the URL, a stream token is
+	if _, res, err := websocket.DefaultDialer.Dial(wsURL+"?token="+hostToken, nil); err == nil || res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expect websockets with a session token query parameter to be refused, got %v", err)
+	}
+	var stream StreamTokenResp
+	decode(t, serve(t, s, http.MethodPost, "/session/stream-token", hostToken, nil), &stream)
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token="+stream.Token, nil)
+	if err != nil {
+		t.Fatalf("Dial() unexpected error %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	readEvent := func(want EventType) Event {
+		var e Event
+		if err := conn.ReadJSON(&e); err != nil {
+			t.Fatalf("ReadJSON() unexpected error %v", err)
+		}
+		if e.Type != want {
+			t.Fatalf("got %s event, want %s", e.Type, want)
+		}
+		return e
+	}
+	if e := readEvent(EventState); e.State.Status != game.StatusWaiting {
+		t.Errorf("snapshot status %q, want %q", e.State.Status, game.StatusWaiting)
+	}
+
+	// the opponent joining is pushed
+	var joined JoinGameResp
+	decode(t, serve(t, s, http.MethodPost, fmt.Sprintf("/games/%s/join", created.GameId), joinerToken, JoinGameReq{PlayerName: "john"}), &joined)
+	if e := readEvent(EventJoin); len(e.State.Players) != 2 {
+		t.Errorf("join event players %+v", e.State.Players)
+	}
+
+	// moves can be played over the socket, errors are answered without closing it
+	if err := conn.WriteJSON(WsPlayMoveReq{Type: "move", PlayerId: created.PlayerId, Row: 1, Column: 1}); err != nil {
+		t.Fatalf("WriteJSON() unexpected error %v", err)
+	}
+	if e := readEvent(EventMove); e.State.Board[1][1] == nil || e.State.Turn != 2 {
+		t.Errorf("move event state %+v", e.State)
+	}
+	if err := conn.WriteJSON(WsPlayMoveReq{Type: "move", PlayerId: created.PlayerId, Row: 0, Column: 0}); err != nil {
+		t.Fatalf("WriteJSON() unexpected error %v", err)
+	}
+	var wsErr WsErrorResp
+	if err := conn.ReadJSON(&wsErr); err != nil || wsErr.Type != "error" || wsErr.Error != game.AnotherPlayerMoveTurnErr.Error() {
+		t.Errorf("got %+v, %v, want the turn error", wsErr, err)
+	}
+
+	// the end of the game is pushed, the joiner abandons and the host wins
+	serve(t, s, http.MethodDelete, fmt.Sprintf("/games/%s", created.GameId), joinerToken, EndGameReq{PlayerId: joined.PlayerId})
+	if e := readEvent(EventEnd); e.State.Winner != 1 || e.State.Reason != game.EndReasonAbandon || e.State.EndTime == nil {
+		t.Errorf("end event state %+v", e.State)
+	}
+}
@@ -0,0 +1,170 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxAnalysisEmptySlots positions with more empty slots are too large to be solved on request
+const MaxAnalysisEmptySlots = 12
+
+// predefined errors
+
+var (
+	AnalysisTooLargeErr     = fmt.Errorf("position too large to analyse. analysis is available with at most %d empty positions", MaxAnalysisEmptySlots)
+	AnalysisInOpeningErr    = errors.New("position can not be analysed during the opening")
+	AnalysisNotSupportedErr = errors.New("position can not be analysed for this game")
+)
+
+// Outcome result of a move under perfect play for the player making it
+type Outcome string
+
+const (
+	OutcomeWin  Outcome = "win"
+	OutcomeDraw Outcome = "draw"
+	OutcomeLoss Outcome = "loss"
+)
+
+// MoveAnalysis result of playing at the position for the player to move
+type MoveAnalysis struct {
+	Position
+	Outcome Outcome `json:"outcome"`
+	// Distance number of moves until the game ends under perfect play, counting this move
+	Distance int `json:"distance"`
+}
+
+// Analyze solves the position and returns the outcome of every legal move for the player to move,
+// an empty result for finished games
+func (g *Game) Analyze() ([]MoveAnalysis, error) {
+	g.mu.Lock()
+	if g.State.End {
+		g.mu.Unlock()
+		return []MoveAnalysis{}, nil
+	}
+	if g.State.Phase != "" {
+		g.mu.Unlock()
+		return nil, AnalysisInOpeningErr
+	}
+	if len(g.Board)*len(g.Board)-g.stoneCount() > MaxAnalysisEmptySlots {
+		g.mu.Unlock()
+		return nil, AnalysisTooLargeErr
+	}
+	// search on a copy so moves are not blocked by the analysis
+	position := g.clone()
+	g.mu.Unlock()
+
+	s := &solver{memo: map[string]solved{}}
+	analysis := []MoveAnalysis{}
+	for _, m := range position.Rules().LegalMoves(position) {
+		result, err := s.solveMove(position, m)
+		if err != nil {
+			return nil, err
+		}
+		analysis = append(analysis, MoveAnalysis{
+			Position: m,
+			Outcome:  result.outcome(),
+			Distance: result.distance,
+		})
+	}
+	return analysis, nil
+}
+
+// solved result for the player to move, score 1 for a win, 0 for a draw and -1 for a loss
+type solved struct {
+	score    int
+	distance int
+}
+
+func (s solved) outcome() Outcome {
+	switch s.score {
+	case 1:
+		return OutcomeWin
+	case -1:
+		return OutcomeLoss
+	default:
+		return OutcomeDraw
+	}
+}
+
+// better prefers wins, then draws, then losses. quicker wins and slower losses are better
+func (s solved) better(other solved) bool {
+	if s.score != other.score {
+		return s.score > other.score
+	}
+	if s.score > 0 {
+		return s.distance < other.distance
+	}
+	return s.distance > other.distance
+}
+
+// solver negamax search with a transposition table
+type solver struct {
+	memo map[string]solved
+}
+
+// solve returns the result of the position for the player to move
+func (s *solver) solve(g *Game) (solved, error) {
+	key := positionKey(g)
+	if result, found := s.memo[key]; found {
+		return result, nil
+	}
+	best := solved{score: -2}
+	for _, m := range g.Rules().LegalMoves(g) {
+		result, err := s.solveMove(g, m)
+		if err != nil {
+			return solved{}, err
+		}
+		if result.better(best) {
+			best = result
+		}
+	}
+	if best.score == -2 {
+		return solved{}, AnalysisNotSupportedErr
+	}
+	s.memo[key] = best
+	return best, nil
+}
+
+// solveMove returns the result of playing m for the player to move
+func (s *solver) solveMove(g *Game, m Position) (solved, error) {
+	mover := g.State.Player2Turn
+	c := g.clone()
+	if err := c.play(m.Row, m.Column); err != nil {
+		return solved{}, err
+	}
+	if c.State.End {
+		switch {
+		case c.State.Draw:
+			return solved{score: 0, distance: 1}, nil
+		case wonBy(c, mover):
+			return solved{score: 1, distance: 1}, nil
+		default:
+			return solved{score: -1, distance: 1}, nil
+		}
+	}
+	if c.State.Player2Turn == mover {
+		return solved{}, AnalysisNotSupportedErr
+	}
+	reply, err := s.solve(c)
+	if err != nil {
+		return solved{}, err
+	}
+	return solved{score: -reply.score, distance: reply.distance + 1}, nil
+}
+
+// positionKey identifies the board, the player to move and the sub-board to play in
+func positionKey(g *Game) string {
+	var b strings.Builder
+	for _, row := range g.Board {
+		for _, mark := range row {
+			b.WriteString(strconv.Itoa(mark + 1))
+		}
+	}
+	b.WriteString(strconv.FormatBool(g.State.Player2Turn))
+	if g.MetaBoard != nil && g.MetaBoard.Next != nil {
+		b.WriteString(fmt.Sprintf("%d%d", g.MetaBoard.Next.Row, g.MetaBoard.Next.Column))
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package game
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestGame_Analyze(t *testing.T) {
+	tests := []struct {
+		name        string
+		board       [][]int
+		state       State
+		want        []MoveAnalysis
+		wantErrType error
+	}{
+		{
+			name:  "player 1 wins now or loses",
+			board: [][]int{{1, 1, 0}, {-1, -1, 0}, {1, -1, 0}},
+			want: []MoveAnalysis{
+				{Position: Position{Row: 0, Column: 2}, Outcome: OutcomeWin, Distance: 1},
+				{Position: Position{Row: 1, Column: 2}, Outcome: OutcomeDraw, Distance: 3},
+				{Position: Position{Row: 2, Column: 2}, Outcome: OutcomeLoss, Distance: 2},
+			},
+		},
+		{
+			name:  "finished game",
+			board: [][]int{{1, 1, 1}, {-1, -1, 0}, {0, 0, 0}},
+			state: State{End: true, Player1Won: true},
+			want:  []MoveAnalysis{},
+		},
+		{
+			name:        "AnalysisTooLargeErr",
+			board:       newBoard(5),
+			wantErrType: AnalysisTooLargeErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{Board: tt.board, State: tt.state, mu: &sync.Mutex{}}
+			for i, row := range tt.board {
+				for j, mark := range row {
+					g.runningSum.add(len(tt.board), i, j, mark)
+				}
+			}
+			got, err := g.Analyze()
+			if !errors.Is(err, tt.wantErrType) {
+				t.Errorf("Analyze() error = %v, wantErr %v", err, tt.wantErrType)
+			}
+			if !reflect.DeepEqual(got, tt.want) && tt.wantErrType == nil {
+				t.Errorf("Analyze() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGame_AnalyzeEmptyBoardIsDraw(t *testing.T) {
+	gf := &NewGameFactory{}
+	g, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	got, err := g.Analyze()
+	if err != nil {
+		t.Fatalf("Analyze() unexpected error %v", err)
+	}
+	if len(got) != 9 {
+		t.Fatalf("Analyze() returns %d moves, want 9", len(got))
+	}
+	for _, m := range got {
+		if m.Outcome != OutcomeDraw || m.Distance != 9 {
+			t.Errorf("Analyze() move %v, want a draw after 9 moves", m)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// bot difficulty levels
+
+const (
+	BotRandom    = "random"
+	BotHeuristic = "heuristic"
+	BotMinimax   = "minimax"
+)
+
+// search limits, positions with few empty slots are searched to the end so the minimax bot plays perfectly there
+const (
+	perfectSearchEmptySlots = 10
+	limitedSearchDepth      = 2
+	// candidateRadius only empty positions this close to a stone are searched on large boards
+	candidateRadius = 1
+	largeBoardMoves = 25
+	winScore        = 1_000_000
+	// metaBoardWeight a sub-board on the ultimate meta-board is worth more than any position inside the sub-boards
+	metaBoardWeight = 1_000
+)
+
+// predefined errors
+
+var (
+	UnknownBotLevelErr = fmt.Errorf("unknown bot level. levels: %s, %s, %s", BotRandom, BotHeuristic, BotMinimax)
+	NoLegalMoveErr     = errors.New("no legal move left")
+)
+
+// Bot server side opponent, always joins the game as player 2
+type Bot interface {
+	// Level the difficulty the bot is selected by
+	Level() string
+	// ChooseMove returns the position the bot plays next in the game, the game is not modified
+	ChooseMove(g *Game) (Position, error)
+}
+
+// NewBot returns a bot for the difficulty level
+func NewBot(level string) (Bot, error) {
+	switch level {
+	case BotRandom:
+		return &RandomBot{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}, nil
+	case BotHeuristic:
+		return &HeuristicBot{}, nil
+	case BotMinimax:
+		return &MinimaxBot{}, nil
+	default:
+		return nil, UnknownBotLevelErr
+	}
+}
+
+// PlayBotTurn lets the bot play for as long as it is its turn in a single player game
+func (g *Game) PlayBotTurn() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.Bot != nil && !g.State.End && g.State.Player2Turn {
+		// the bot always keeps the second color offered by the swap2 opening
+		if g.inOpeningChoice() {
+			if err := g.chooseColor(ChooseO); err != nil {
+				return err
+			}
+			g.record(true, 0, 0, ChooseO)
+			continue
+		}
+		p, err := g.Bot.ChooseMove(g)
+		if err != nil {
+			return err
+		}
+		if err := g.play(p.Row, p.Column); err != nil {
+			return err
+		}
+		g.record(true, p.Row, p.Column, "")
+	}
+	return nil
+}
+
+// RandomBot plays any legal move
+type RandomBot struct {
+	rand *rand.Rand
+}
+
+func (b *RandomBot) Level() string {
+	return BotRandom
+}
+
+func (b *RandomBot) ChooseMove(g *Game) (Position, error) {
+	moves := g.Rules().LegalMoves(g)
+	if len(moves) == 0 {
+		return Position{}, NoLegalMoveErr
+	}
+	return moves[b.rand.Intn(len(moves))], nil
+}
+
+// HeuristicBot wins if it can, blocks the opponent's winning move, otherwise plays the position with the best line
+// potential that does not lose the game right away
+type HeuristicBot struct{}
+
+func (b *HeuristicBot) Level() string {
+	return BotHeuristic
+}
+
+func (b *HeuristicBot) ChooseMove(g *Game) (Position, error) {
+	moves := candidateMoves(g)
+	if len(moves) == 0 {
+		return Position{}, NoLegalMoveErr
+	}
+	player2 := g.State.Player2Turn
+	// winning move
+	for _, m := range moves {
+		c := g.clone()
+		if c.play(m.Row, m.Column) == nil && wonBy(c, player2) {
+			return m, nil
+		}
+	}
+	// block the opponent's winning move by checking what the opponent would get if it was its turn
+	for _, m := range moves {
+		c := g.clone()
+		c.State.Player2Turn = !player2
+		if c.play(m.Row, m.Column) == nil && wonBy(c, !player2) {
+			return m, nil
+		}
+	}
+	best, bestScore := moves[0], math.MinInt
+	for _, m := range moves {
+		c := g.clone()
+		if c.play(m.Row, m.Column) != nil {
+			continue
+		}
+		score := evaluate(c, player2)
+		if c.State.End && wonBy(c, !player2) {
+			// completing a line of a misere game
+			score = -winScore
+		}
+		if score > bestScore {
+			best, bestScore = m, score
+		}
+	}
+	return best, nil
+}
+
+// MinimaxBot searches the game tree with alpha-beta pruning. positions with few empty slots are searched to
+// the end, which makes the bot unbeatable on a 3 x 3 board, larger boards are searched to a limited depth
+type MinimaxBot struct{}
+
+func (b *MinimaxBot) Level() string {
+	return BotMinimax
+}
+
+func (b *MinimaxBot) ChooseMove(g *Game) (Position, error) {
+	moves := candidateMoves(g)
+	if len(moves) == 0 {
+		return Position{}, NoLegalMoveErr
+	}
+	depth := limitedSearchDepth
+	if len(g.Rules().LegalMoves(g)) <= perfectSearchEmptySlots {
+		depth = perfectSearchEmptySlots
+	}
+	player2 := g.State.Player2Turn
+	best, bestScore := moves[0], math.MinInt
+	alpha := math.MinInt + 1
+	for _, m := range moves {
+		c := g.clone()
+		if c.play(m.Row, m.Column) != nil {
+			continue
+		}
+		score := minimax(c, depth-1, alpha, math.MaxInt, player2)
+		if score > bestScore {
+			best, bestScore = m, score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return best, nil
+}
+
+// minimax returns the score of the game for player 2 if player2 is set, for player 1 otherwise.
+// wins score higher the sooner they happen
+func minimax(g *Game, depth int, alpha int, beta int, player2 bool) int {
+	if g.State.End {
+		switch {
+		case g.State.Draw:
+			return 0
+		case wonBy(g, player2):
+			return winScore + depth
+		default:
+			return -winScore - depth
+		}
+	}
+	if depth <= 0 {
+		return evaluate(g, player2)
+	}
+	maximizing := g.State.Player2Turn == player2
+	best := math.MaxInt
+	if maximizing {
+		best = math.MinInt
+	}
+	for _, m := range candidateMoves(g) {
+		c := g.clone()
+		if c.play(m.Row, m.Column) != nil {
+			continue
+		}
+		score := minimax(c, depth-1, alpha, beta, player2)
+		if maximizing {
+			if score > best {
+				best = score
+			}
+			if best > alpha {
+				alpha = best
+			}
+		} else {
+			if score < best {
+				best = score
+			}
+			if best < beta {
+				beta = best
+			}
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// wonBy checks if player 2 won the game if player2 is set, player 1 otherwise
+func wonBy(g *Game, player2 bool) bool {
+	if player2 {
+		return g.State.Player2Won
+	}
+	return g.State.Player1Won
+}
+
+// evaluate scores the board for player 2 if player2 is set, for player 1 otherwise. every window of win length
+// positions holding marks of only one player counts for that player, more marks weigh exponentially more.
+// ultimate games are scored on the meta-board first and the open sub-boards second, in misere games every
+// window counts against the player whose marks it holds
+func evaluate(g *Game, player2 bool) int {
+	mark := g.playerMark(player2)
+	var score int
+	if g.MetaBoard != nil {
+		score = scoreWindows(g.MetaBoard.Winners, subBoardSize, mark) * metaBoardWeight
+		for i, winners := range g.MetaBoard.Winners {
+			for j, winner := range winners {
+				if winner == 0 {
+					score += scoreWindows(subBoard(g.Board, i, j), subBoardSize, mark)
+				}
+			}
+		}
+	} else {
+		score = scoreWindows(g.Board, g.winLength(), mark)
+	}
+	if l, ok := g.Rules().(LineLoser); ok && l.CompletedLineLoses() {
+		return -score
+	}
+	return score
+}
+
+// scoreWindows scores every window of k positions of the board for the mark, positions holding anything but the
+// two marks block the window
+func scoreWindows(board [][]int, k int, mark int) int {
+	n := len(board)
+	directions := [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	var score int
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			for _, d := range directions {
+				endRow, endCol := i+d[0]*(k-1), j+d[1]*(k-1)
+				if endRow < 0 || endCol < 0 || endRow >= n || endCol >= n {
+					continue
+				}
+				var own, other, blocked int
+				for step := 0; step < k; step++ {
+					switch board[i+d[0]*step][j+d[1]*step] {
+					case 0:
+					case mark:
+						own++
+					case -mark:
+						other++
+					default:
+						blocked++
+					}
+				}
+				if blocked > 0 {
+					continue
+				}
+				if other == 0 && own > 0 {
+					score += windowWeight(own)
+				} else if own == 0 && other > 0 {
+					score -= windowWeight(other)
+				}
+			}
+		}
+	}
+	return score
+}
+
+// subBoard returns the positions of the sub-board of an ultimate game board
+func subBoard(board [][]int, row int, col int) [][]int {
+	sub := make([][]int, subBoardSize)
+	for i := range sub {
+		sub[i] = board[row*subBoardSize+i][col*subBoardSize : (col+1)*subBoardSize]
+	}
+	return sub
+}
+
+func windowWeight(marks int) int {
+	weight := 1
+	for i := 1; i < marks; i++ {
+		weight *= 10
+	}
+	return weight
+}
+
+// candidateMoves returns the legal moves worth searching. on large boards only positions next to a stone are kept
+func candidateMoves(g *Game) []Position {
+	moves := g.Rules().LegalMoves(g)
+	if len(moves) <= largeBoardMoves {
+		return moves
+	}
+	n := len(g.Board)
+	var nearby []Position
+	for _, m := range moves {
+		found := false
+		for r := m.Row - candidateRadius; r <= m.Row+candidateRadius && !found; r++ {
+			for c := m.Column - candidateRadius; c <= m.Column+candidateRadius && !found; c++ {
+				found = r >= 0 && c >= 0 && r < n && c < n && g.Board[r][c] != 0
+			}
+		}
+		if found {
+			nearby = append(nearby, m)
+		}
+	}
+	if len(nearby) == 0 {
+		// empty board, start in the center
+		center := Position{Row: n / 2, Column: n / 2}
+		for _, m := range moves {
+			if m == center {
+				return []Position{center}
+			}
+		}
+		return moves
+	}
+	return nearby
+}
@@ -0,0 +1,193 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func newTestBotGame(t *testing.T, gf *NewGameFactory) *Game {
+	g, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	return g
+}
+
+func TestNewBot(t *testing.T) {
+	tests := []struct {
+		name        string
+		level       string
+		wantErrType error
+	}{
+		{name: "random", level: BotRandom},
+		{name: "heuristic", level: BotHeuristic},
+		{name: "minimax", level: BotMinimax},
+		{name: "UnknownBotLevelErr", level: "grandmaster", wantErrType: UnknownBotLevelErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewBot(tt.level)
+			if !errors.Is(err, tt.wantErrType) {
+				t.Errorf("NewBot() error = %v, wantErr %v", err, tt.wantErrType)
+				return
+			}
+			if err == nil && got.Level() != tt.level {
+				t.Errorf("NewBot() level = %s, want %s", got.Level(), tt.level)
+			}
+		})
+	}
+}
+
+func TestBot_ChooseMove(t *testing.T) {
+	tests := []struct {
+		name  string
+		bot   Bot
+		board [][]int
+		want  Position
+	}{
+		{
+			name:  "minimax takes the win",
+			bot:   &MinimaxBot{},
+			board: [][]int{{1, 1, 0}, {-1, -1, 0}, {1, 0, 0}},
+			want:  Position{Row: 1, Column: 2},
+		},
+		{
+			name:  "minimax blocks",
+			bot:   &MinimaxBot{},
+			board: [][]int{{1, 1, 0}, {0, -1, 0}, {0, 0, 0}},
+			want:  Position{Row: 0, Column: 2},
+		},
+		{
+			name:  "heuristic takes the win",
+			bot:   &HeuristicBot{},
+			board: [][]int{{1, 1, 0}, {-1, -1, 0}, {1, 0, 0}},
+			want:  Position{Row: 1, Column: 2},
+		},
+		{
+			name:  "heuristic blocks",
+			bot:   &HeuristicBot{},
+			board: [][]int{{1, 0, 0}, {0, 1, 0}, {0, -1, 0}},
+			want:  Position{Row: 2, Column: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{Board: tt.board, State: State{Player2Turn: true}}
+			for i, row := range tt.board {
+				for j, mark := range row {
+					g.runningSum.add(len(tt.board), i, j, mark)
+				}
+			}
+			got, err := tt.bot.ChooseMove(g)
+			if err != nil {
+				t.Fatalf("ChooseMove() unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChooseMove() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGame_PlayBotTurn(t *testing.T) {
+	g := newTestBotGame(t, &NewGameFactory{Bot: BotMinimax})
+	if g.Player2Id == "" || g.Player2Name != "minimax bot" {
+		t.Fatalf("expect the bot to join as player 2, got %q %q", g.Player2Id, g.Player2Name)
+	}
+	if err := g.Move(g.Player1Id, 1, 1); err != nil {
+		t.Fatalf("Move() unexpected error %v", err)
+	}
+	if err := g.PlayBotTurn(); err != nil {
+		t.Fatalf("PlayBotTurn() unexpected error %v", err)
+	}
+	if g.State.Player2Turn || g.stoneCount() != 2 {
+		t.Errorf("expect the bot to reply with one move, state %v board %v", g.State, g.Board)
+	}
+}
+
+func TestMinimaxBot_NeverLoses(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		g := newTestBotGame(t, &NewGameFactory{Bot: BotMinimax})
+		for !g.State.End {
+			moves := g.LegalMoves()
+			m := moves[r.Intn(len(moves))]
+			if err := g.Move(g.Player1Id, m.Row, m.Column); err != nil {
+				t.Fatalf("Move() unexpected error %v", err)
+			}
+			if err := g.PlayBotTurn(); err != nil {
+				t.Fatalf("PlayBotTurn() unexpected error %v", err)
+			}
+		}
+		if g.State.Player1Won {
+			t.Fatalf("minimax bot lost game %d, board %v", i, g.Board)
+		}
+	}
+}
+
+func TestBot_GomokuSwap2(t *testing.T) {
+	g := newTestBotGame(t, &NewGameFactory{Variant: "gomoku", Opening: Swap2Opening, Bot: BotHeuristic})
+	for _, col := range []int{6, 7, 8} {
+		if err := g.Move(g.Player1Id, 7, col); err != nil {
+			t.Fatalf("Move() unexpected error %v", err)
+		}
+	}
+	if err := g.PlayBotTurn(); err != nil {
+		t.Fatalf("PlayBotTurn() unexpected error %v", err)
+	}
+	if g.State.Phase != "" || g.State.Player2Turn || g.stoneCount() != 4 {
+		t.Errorf("expect the bot to take O and place a stone, state %+v", g.State)
+	}
+}
+
+func TestHeuristicBot_Variants(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant string
+		moves   []Position
+		want    *Position
+		avoid   *Position
+	}{
+		{
+			name:    "misere does not complete the own line",
+			variant: "misere",
+			moves:   []Position{{0, 0}, {1, 0}, {0, 2}, {1, 1}, {2, 1}},
+			avoid:   &Position{Row: 1, Column: 2},
+		},
+		{
+			name:    "ultimate wins the sub-board",
+			variant: "ultimate",
+			moves:   []Position{{3, 3}, {0, 1}, {0, 3}, {0, 2}, {0, 6}},
+			want:    &Position{Row: 0, Column: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestBotGame(t, &NewGameFactory{Variant: tt.variant})
+			if err := g.Join(g.Id, "test_player2_id", "john"); err != nil {
+				t.Fatalf("Join() unexpected error %v", err)
+			}
+			for i, m := range tt.moves {
+				playerId := g.Player1Id
+				if i%2 == 1 {
+					playerId = g.Player2Id
+				}
+				if err := g.Move(playerId, m.Row, m.Column); err != nil {
+					t.Fatalf("Move() unexpected error %v", err)
+				}
+			}
+			got, err := (&HeuristicBot{}).ChooseMove(g)
+			if err != nil {
+				t.Fatalf("ChooseMove() unexpected error %v", err)
+			}
+			if tt.want != nil && got != *tt.want {
+				t.Errorf("ChooseMove() = %v, want %v", got, *tt.want)
+			}
+			if tt.avoid != nil && got == *tt.avoid {
+				t.Errorf("ChooseMove() = %v, want any other move", got)
+			}
+		})
+	}
+}
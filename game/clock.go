@@ -0,0 +1,155 @@
+package game
+
+import (
+	"errors"
+	"time"
+)
+
+// predefined errors
+
+var (
+	InvalidTimeControlErr = errors.New("invalid time control. either a time per move, or an initial time with an optional increment per move")
+	TimeUpErr             = errors.New("time is up. the game is lost on time")
+)
+
+// TimeControl clock settings of a game, either a fixed time for every move or an initial time
+// with an increment added after each move
+type TimeControl struct {
+	// PerMove time each move has to be made in, the clock is reset after every move
+	PerMove time.Duration
+	// Initial time on each clock at the start of the game
+	Initial time.Duration
+	// Increment time added to the clock of a player after each of the player's moves
+	Increment time.Duration
+}
+
+// validate checks the time control is either per move or initial time with increment
+func (tc *TimeControl) validate() error {
+	if tc.PerMove < 0 || tc.Initial < 0 || tc.Increment < 0 {
+		return InvalidTimeControlErr
+	}
+	if (tc.PerMove > 0) == (tc.Initial > 0) || (tc.PerMove > 0 && tc.Increment > 0) {
+		return InvalidTimeControlErr
+	}
+	return nil
+}
+
+// start returns the time on each clock when the game starts
+func (tc *TimeControl) start() time.Duration {
+	if tc.PerMove > 0 {
+		return tc.PerMove
+	}
+	return tc.Initial
+}
+
+// startClock starts the clocks once both players are seated, the caller holds the lock
+func (g *Game) startClock(now time.Time) {
+	if g.TimeControl == nil {
+		return
+	}
+	g.Clocks = [2]time.Duration{g.TimeControl.start(), g.TimeControl.start()}
+	g.turnStarted = now
+	g.armFlagTimer()
+}
+
+// switchClock charges the time since the turn started to the player who just moved and starts the clock
+// of the player to move, the caller holds the lock
+func (g *Game) switchClock(player2 bool, now time.Time) {
+	if g.TimeControl == nil || g.turnStarted.IsZero() {
+		return
+	}
+	mover := 0
+	if player2 {
+		mover = 1
+	}
+	g.Clocks[mover] -= now.Sub(g.turnStarted)
+	if g.TimeControl.PerMove > 0 {
+		g.Clocks[mover] = g.TimeControl.PerMove
+	} else {
+		g.Clocks[mover] += g.TimeControl.Increment
+	}
+	g.turnStarted = now
+	if g.State.End {
+		g.stopClock()
+		return
+	}
+	g.armFlagTimer()
+}
+
+// resumeClock starts the clock of the player to move at now after the turn changed without a move,
+// the caller holds the lock
+func (g *Game) resumeClock(now time.Time) {
+	if g.TimeControl == nil || g.turnStarted.IsZero() {
+		return
+	}
+	if g.TimeControl.PerMove > 0 {
+		g.Clocks = [2]time.Duration{g.TimeControl.PerMove, g.TimeControl.PerMove}
+	}
+	g.turnStarted = now
+	g.armFlagTimer()
+}
+
+// remaining returns the time left on the clocks at now, only the clock of the player to move runs
+func (g *Game) remaining(now time.Time) [2]time.Duration {
+	clocks := g.Clocks
+	if g.TimeControl == nil || g.turnStarted.IsZero() || g.State.End {
+		return clocks
+	}
+	toMove := 0
+	if g.State.Player2Turn {
+		toMove = 1
+	}
+	clocks[toMove] -= now.Sub(g.turnStarted)
+	if clocks[toMove] < 0 {
+		clocks[toMove] = 0
+	}
+	return clocks
+}
+
+// flagged checks if the player to move ran out of time at now
+func (g *Game) flagged(now time.Time) bool {
+	if g.TimeControl == nil || g.turnStarted.IsZero() || g.State.End {
+		return false
+	}
+	clocks := g.remaining(now)
+	if g.State.Player2Turn {
+		return clocks[1] <= 0
+	}
+	return clocks[0] <= 0
+}
+
+// checkFlag ends the game with a loss for the player to move if the player ran out of time, the caller holds the lock
+func (g *Game) checkFlag(now time.Time) error {
+	if !g.flagged(now) {
+		return nil
+	}
+	g.end(now, EndReasonTimeout, g.State.Player2Turn, !g.State.Player2Turn, false)
+	if g.onFlagFall != nil {
+		// the callback may read the game, so it runs once the lock is released
+		go g.onFlagFall(g)
+	}
+	return TimeUpErr
+}
+
+// armFlagTimer schedules the flag fall of the player to move even if nobody calls the game, the caller holds the lock
+func (g *Game) armFlagTimer() {
+	g.stopClock()
+	clocks := g.remaining(g.turnStarted)
+	left := clocks[0]
+	if g.State.Player2Turn {
+		left = clocks[1]
+	}
+	g.flagTimer = time.AfterFunc(left, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.checkFlag(time.Now())
+	})
+}
+
+// stopClock stops the flag fall timer, the caller holds the lock
+func (g *Game) stopClock() {
+	if g.flagTimer != nil {
+		g.flagTimer.Stop()
+		g.flagTimer = nil
+	}
+}
@@ -0,0 +1,121 @@
+package game
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeControl_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tc      TimeControl
+		wantErr error
+	}{
+		{name: "per move", tc: TimeControl{PerMove: 30 * time.Second}},
+		{name: "initial with increment", tc: TimeControl{Initial: 3 * time.Minute, Increment: 2 * time.Second}},
+		{name: "initial without increment", tc: TimeControl{Initial: time.Minute}},
+		{name: "no time", tc: TimeControl{}, wantErr: InvalidTimeControlErr},
+		{name: "increment only", tc: TimeControl{Increment: time.Second}, wantErr: InvalidTimeControlErr},
+		{name: "per move and initial", tc: TimeControl{PerMove: time.Second, Initial: time.Minute}, wantErr: InvalidTimeControlErr},
+		{name: "per move with increment", tc: TimeControl{PerMove: time.Second, Increment: time.Second}, wantErr: InvalidTimeControlErr},
+		{name: "negative", tc: TimeControl{Initial: -time.Minute}, wantErr: InvalidTimeControlErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tc.validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func newTestTimedGame(t *testing.T, tc *TimeControl, onFlagFall func(*Game)) *Game {
+	gf := &NewGameFactory{TimeControl: tc, OnFlagFall: onFlagFall}
+	g, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	if err := g.Join(g.Id, "test_player2_id", "john"); err != nil {
+		t.Fatalf("Join() unexpected error %v", err)
+	}
+	t.Cleanup(func() {
+		g.mu.Lock()
+		g.stopClock()
+		g.mu.Unlock()
+	})
+	return g
+}
+
+func TestGame_Clock(t *testing.T) {
+	tests := []struct {
+		name string
+		tc   *TimeControl
+		// spent time player 1 takes for the first move
+		spent      time.Duration
+		wantClocks [2]time.Duration
+	}{
+		{
+			name:       "per move clock is reset after the move",
+			tc:         &TimeControl{PerMove: 30 * time.Second},
+			spent:      10 * time.Second,
+			wantClocks: [2]time.Duration{30 * time.Second, 30 * time.Second},
+		},
+		{
+			name:       "increment is added after the move",
+			tc:         &TimeControl{Initial: time.Minute, Increment: 2 * time.Second},
+			spent:      10 * time.Second,
+			wantClocks: [2]time.Duration{52 * time.Second, time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestTimedGame(t, tt.tc, nil)
+			g.mu.Lock()
+			g.turnStarted = g.turnStarted.Add(-tt.spent)
+			g.mu.Unlock()
+			if err := g.Move(g.Player1Id, 0, 0); err != nil {
+				t.Fatalf("Move() unexpected error %v", err)
+			}
+			for i, want := range tt.wantClocks {
+				if diff := g.Clocks[i] - want; diff > time.Second || diff < -time.Second {
+					t.Errorf("Clocks[%d] = %v, want %v", i, g.Clocks[i], want)
+				}
+			}
+			if v := g.View(); v.Clock == nil || v.Clock.RemainingMs[1] > tt.wantClocks[1].Milliseconds() {
+				t.Errorf("View().Clock = %+v, want player 2 clock running from %v", v.Clock, tt.wantClocks[1])
+			}
+		})
+	}
+}
+
+func TestGame_FlagFall(t *testing.T) {
+	t.Run("the timer ends the game without a call", func(t *testing.T) {
+		fell := make(chan *Game, 1)
+		g := newTestTimedGame(t, &TimeControl{PerMove: 20 * time.Millisecond}, func(g *Game) { fell <- g })
+		select {
+		case <-fell:
+		case <-time.After(2 * time.Second):
+			t.Fatal("flag did not fall")
+		}
+		v := g.View()
+		if v.Winner != 2 || v.Reason != EndReasonTimeout {
+			t.Errorf("View() winner = %d reason = %q, want player 2 winning on time", v.Winner, v.Reason)
+		}
+	})
+	t.Run("a move after the time ran out loses", func(t *testing.T) {
+		g := newTestTimedGame(t, &TimeControl{Initial: time.Minute}, nil)
+		g.mu.Lock()
+		g.turnStarted = g.turnStarted.Add(-2 * time.Minute)
+		g.mu.Unlock()
+		if err := g.Move(g.Player1Id, 0, 0); !errors.Is(err, TimeUpErr) {
+			t.Fatalf("Move() error = %v, want %v", err, TimeUpErr)
+		}
+		if !g.State.End || !g.State.Player2Won || g.State.EndReason != EndReasonTimeout {
+			t.Errorf("State = %+v, want player 2 winning on time", g.State)
+		}
+		if err := g.Move(g.Player1Id, 0, 0); !errors.Is(err, GameAlreadyFinishedErr) {
+			t.Errorf("Move() error = %v, want %v", err, GameAlreadyFinishedErr)
+		}
+	})
+}
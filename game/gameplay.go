@@ -16,19 +16,63 @@ type Game struct {
 	Player1Name string
 	Player2Id   string
 	Player2Name string
+	// Player1AccountId and Player2AccountId accounts of the players, empty for guests and bots
+	Player1AccountId string
+	Player2AccountId string
 
-	Board      [3][3]int
-	State      State
-	runningSum RunningSum
+	// Board n x n board, player 1 is represented by 1, player 2 by -1 and empty slot by 0
+	Board [][]int
+	// WinLength number of marks in a row needed to win, defaults to the board size
+	WinLength int
+	// ExactWinLength only lines of exactly win length win, longer lines (overlines) do not count
+	ExactWinLength bool
+	// MetaBoard state of the sub-boards for variants played on sub-boards, nil otherwise
+	MetaBoard *MetaBoard
+	State     State
+	// Moves history of the game in the order played
+	Moves []MoveRecord
+	// initialState state before the first move, the starting point of replays
+	initialState State
+	runningSum   RunningSum
+	// Bot server side opponent playing as player 2, nil when two people play
+	Bot Bot
+	// Version increases with every change of the game, clients wait for it to move past the version they know
+	Version int
+	// changed closed and replaced when the version increases
+	changed chan struct{}
+	// TimeControl clock settings, nil for untimed games
+	TimeControl *TimeControl
+	// Clocks time left for player 1 and player 2 when the turn started
+	Clocks [2]time.Duration
+	// turnStarted when the clock of the player to move started, zero until both players are seated
+	turnStarted time.Time
+	// flagTimer ends the game when the player to move runs out of time
+	flagTimer *time.Timer
+	// onFlagFall called once the game was lost on time
+	onFlagFall func(*Game)
+	// PreviousGameId game this game is a rematch of, empty otherwise
+	PreviousGameId string
+	// RematchGameId rematch created once both players asked for it after the game finished
+	RematchGameId string
+	// RematchRequestedBy player 1 or 2 waiting for the opponent to ask for a rematch too, 0 if none
+	RematchRequestedBy int
+	// Private hidden from spectators and from the open games list
+	Private bool
+	// Ratings ratings of player 1 and player 2 in a rated game, nil until set
+	Ratings [2]*PlayerRating
+	// spectators number of clients watching the game
+	spectators int
+	// rules of the game variant, classic rules are used if not set
+	rules Rules
 
 	mu *sync.Mutex
 }
 
 // RunningSum storing and incrementing the sum of rows, cols, diagonals for each move,
-// we know there is a win if one of the sum grows to 3
+// we know there is a win if one of the sum grows to n when a full row is needed to win
 type RunningSum struct {
-	rowSum             [3]int
-	columnSum          [3]int
+	rowSum             []int
+	columnSum          []int
 	diagonalSum        int
 	reverseDiagonalSum int
 }
@@ -40,6 +84,16 @@ type State struct {
 	Player2Won  bool
 	Draw        bool
 	EndTime     time.Time
+	// Phase opening phase of the game, empty when the game has no opening or it is over
+	Phase Phase
+	// ColorsSwapped player 1 plays O and player 2 plays X after a color choice in the opening
+	ColorsSwapped bool
+	// TakebackRequestedBy player 1 or 2 waiting for the opponent to answer a takeback request, 0 if none
+	TakebackRequestedBy int
+	// EndReason how the game ended, empty while in progress
+	EndReason EndReason
+	// DrawOfferedBy player 1 or 2 waiting for the opponent to answer a draw offer, 0 if none
+	DrawOfferedBy int
 }
 
 // predefined errors
@@ -52,23 +106,100 @@ var (
 	DuplicatePlayerNameErr     = errors.New("player name already taken. please use another name")
 	GameAlreadyFinishedErr     = errors.New("game finished")
 	AnotherPlayerMoveTurnErr   = errors.New("invalid move. Please wait for other player to move")
-	InvalidMoveErr             = errors.New("invalid move. constraints: 0 <= row < 3, 0 <= column < 3")
+	InvalidMoveErr             = errors.New("invalid move. constraints: 0 <= row < board size, 0 <= column < board size")
 	MovePositionFilledErr      = errors.New("invalid move. position is filled")
+	InvalidBoardSizeErr        = fmt.Errorf("invalid board size. constraints: %d <= board size <= %d", MinBoardSize, MaxBoardSize)
+	InvalidWinLengthErr        = fmt.Errorf("invalid win length. constraints: %d <= win length <= board size", MinBoardSize)
+	UnknownVariantErr          = errors.New("unknown game variant")
 )
 
-type NewGameFactory struct{}
+// NewGameFactory creates new games, zero values fall back to the classic 3 x 3 three in a row game
+type NewGameFactory struct {
+	// Variant name of the registered rules to play with, defaults to classic
+	Variant string
+	// Size board size n for a n x n board, defaults to the variant's board size
+	Size int
+	// WinLength number of marks in a row needed to win, defaults to the variant's win length
+	WinLength int
+	// ExactWinLength overlines longer than win length do not win
+	ExactWinLength bool
+	// Opening optional opening protocol, only swap2 for the gomoku variant
+	Opening string
+	// Bot optional bot level, the bot joins right away as player 2
+	Bot string
+	// TimeControl optional clock settings, the clocks start once both players are seated
+	TimeControl *TimeControl
+	// OnFlagFall optional callback once a timed game was lost on time, called without holding the game lock
+	OnFlagFall func(*Game)
+	// Private hides the game from spectators and from the open games list
+	Private bool
+	// AccountId optional account of the host
+	AccountId string
+}
 
-func (gf *NewGameFactory) CreateGame(playerName string) *Game {
-	return &Game{
-		Id:          uuid.NewString(),
-		Player1Id:   uuid.NewString(),
-		Player1Name: playerName,
-		mu:          &sync.Mutex{},
+func (gf *NewGameFactory) CreateGame(playerName string) (*Game, error) {
+	variant := gf.Variant
+	if variant == "" {
+		variant = DefaultVariant
+	}
+	rules, err := LookupRules(variant)
+	if err != nil {
+		return nil, err
+	}
+	size, winLength, err := rules.Configure(gf.Size, gf.WinLength)
+	if err != nil {
+		return nil, err
+	}
+	var state State
+	if gf.Opening != "" {
+		if gf.Opening != Swap2Opening || rules.Name() != (Gomoku{}).Name() {
+			return nil, InvalidOpeningErr
+		}
+		state.Phase = PhaseSwap2PlaceThree
+	}
+	if gf.TimeControl != nil {
+		if err := gf.TimeControl.validate(); err != nil {
+			return nil, err
+		}
 	}
+	g := &Game{
+		Id:               uuid.NewString(),
+		Player1Id:        uuid.NewString(),
+		Player1Name:      playerName,
+		Player1AccountId: gf.AccountId,
+		Board:            newBoard(size),
+		WinLength:        winLength,
+		ExactWinLength:   gf.ExactWinLength,
+		State:            state,
+		initialState:     state,
+		rules:            rules,
+		TimeControl:      gf.TimeControl,
+		onFlagFall:       gf.OnFlagFall,
+		Private:          gf.Private,
+		mu:               &sync.Mutex{},
+	}
+	if gf.Bot != "" {
+		bot, err := NewBot(gf.Bot)
+		if err != nil {
+			return nil, err
+		}
+		g.Bot = bot
+		g.Player2Id = uuid.NewString()
+		g.Player2Name = fmt.Sprintf("%s bot", bot.Level())
+		g.startClock(time.Now())
+	}
+	return g, nil
 }
 
 // Join player can join a game
 func (g *Game) Join(gameId string, playerId string, playerName string) error {
+	return g.JoinAsAccount(gameId, playerId, playerName, "")
+}
+
+// JoinAsAccount player signed in to the account can join a game, an account can not play against itself
+func (g *Game) JoinAsAccount(gameId string, playerId string, playerName string, accountId string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	if gameId != g.Id {
 		return GameIdNotfoundErr
 	}
@@ -78,6 +209,9 @@ func (g *Game) Join(gameId string, playerId string, playerName string) error {
 	if playerId == g.Player1Id || playerId == g.Player2Id {
 		return AlreadyJoinGameErr
 	}
+	if accountId != "" && accountId == g.Player1AccountId {
+		return AlreadyJoinGameErr
+	}
 	if g.Player2Id != "" {
 		return GameFilledWithMaxPlayerErr
 	}
@@ -88,14 +222,32 @@ func (g *Game) Join(gameId string, playerId string, playerName string) error {
 
 	g.Player2Id = playerId
 	g.Player2Name = playerName
+	g.Player2AccountId = accountId
+	g.startClock(time.Now())
+	g.bump()
 	return nil
 }
 
+// Ended checks if the game is over
+func (g *Game) Ended() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.State.End
+}
+
+// Rules returns the rules the game is played with
+func (g *Game) Rules() Rules {
+	if g.rules == nil {
+		return Classic{}
+	}
+	return g.rules
+}
+
 // Move makes a move on the game board and record the state of the game
-// Player 1's move will be represented by 1 and player 2's move will be represented by -1, empty slot represented by 0
+// Player 1's move will be represented by 1 and player 2's move will be represented by -1, empty slot represented by 0,
+// unless the colors were swapped in the opening. During an opening placement the player to move places both marks
+// Legality of the move, how it is placed and when the game ends are decided by the game's rules
 func (g *Game) Move(playerId string, row int, col int) error {
-	// size of board
-	var n int = 3
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	// state check
@@ -109,86 +261,188 @@ func (g *Game) Move(playerId string, row int, col int) error {
 	if (g.State.Player2Turn && playerId != g.Player2Id) || (!g.State.Player2Turn && playerId != g.Player1Id) {
 		return AnotherPlayerMoveTurnErr
 	}
-
-	// boundary check
-	if row < 0 || col < 0 || row >= n || col >= n {
-		return InvalidMoveErr
+	if err := g.checkFlag(time.Now()); err != nil {
+		return err
+	}
+	if g.State.TakebackRequestedBy != 0 {
+		return TakebackPendingErr
 	}
-	// position filled check
-	if g.Board[row][col] != 0 {
-		return MovePositionFilledErr
+	player2 := g.State.Player2Turn
+	if err := g.play(row, col); err != nil {
+		return err
+	}
+	g.record(player2, row, col, "")
+	return nil
+}
+
+// play places the mark of the player to move at row, col and updates the state, the caller holds the lock
+func (g *Game) play(row int, col int) error {
+	if g.inOpeningChoice() {
+		return ColorChoicePendingErr
 	}
 
 	// fill position for current move
-	move := 1
-	if g.State.Player2Turn {
-		move = -1
+	move := g.playerMark(g.State.Player2Turn)
+	if g.inOpeningPlacement() {
+		move = g.markToMove()
 	}
-	g.Board[row][col] = move
-	g.runningSum.rowSum[row] += move
-	g.runningSum.columnSum[col] += move
-	if row == col {
-		g.runningSum.diagonalSum += move
+	rules := g.Rules()
+	if err := rules.ApplyMove(g, row, col, move); err != nil {
+		return err
 	}
-	// reverse diagonal position pattern row = n - 1- column
-	if row == n-1-col {
-		g.runningSum.reverseDiagonalSum += move
+	// the same player keeps placing until the opening asks for a color choice
+	if g.inOpeningPlacement() {
+		g.advanceOpening()
+		return nil
 	}
 
-	// check for wins
-	if math.Abs(float64(g.runningSum.rowSum[row])) == float64(n) ||
-		math.Abs(float64(g.runningSum.columnSum[row])) == float64(n) ||
-		math.Abs(float64(g.runningSum.diagonalSum)) == float64(n) ||
-		math.Abs(float64(g.runningSum.reverseDiagonalSum)) == float64(n) {
+	// check for wins or draw
+	if end, winner := rules.Terminal(g, row, col); end {
 		g.State.End = true
-		if g.State.Player2Turn {
-			g.State.Player2Won = true
-		} else {
+		g.State.EndReason = EndReasonBoard
+		switch winner {
+		case g.playerMark(false):
 			g.State.Player1Won = true
+		case g.playerMark(true):
+			g.State.Player2Won = true
+		default:
+			g.State.Draw = true
 		}
 		g.State.EndTime = time.Now()
 		return nil
 	}
 
-	// check if it is a draw - all position filled
-	var emptySlot bool
-	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
-			if g.Board[i][j] == 0 {
-				emptySlot = true
-			}
-		}
+	// game keep going, switch handle to the next player
+	g.State.Player2Turn = !g.State.Player2Turn
+	return nil
+}
+
+// clone returns a deep copy of the board and state of the game without its bot, used to search moves
+func (g *Game) clone() *Game {
+	c := *g
+	c.Board = make([][]int, len(g.Board))
+	for i, row := range g.Board {
+		c.Board[i] = append([]int(nil), row...)
 	}
-	if !emptySlot {
-		g.State.End = true
-		g.State.Draw = true
-		g.State.EndTime = time.Now()
+	c.runningSum.rowSum = append([]int(nil), g.runningSum.rowSum...)
+	c.runningSum.columnSum = append([]int(nil), g.runningSum.columnSum...)
+	c.MetaBoard = g.MetaBoard.copy()
+	// history is not needed to search moves
+	c.Moves = nil
+	c.Bot = nil
+	c.changed = nil
+	c.TimeControl = nil
+	c.flagTimer = nil
+	c.onFlagFall = nil
+	c.mu = &sync.Mutex{}
+	return &c
+}
+
+// playerMark returns the mark of player 2 or player 1, 1 for X and -1 for O
+func (g *Game) playerMark(player2 bool) int {
+	if player2 != g.State.ColorsSwapped {
+		return -1
+	}
+	return 1
+}
+
+// LegalMoves returns the positions the player to move can play
+func (g *Game) LegalMoves() []Position {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.State.End {
 		return nil
 	}
+	return g.Rules().LegalMoves(g)
+}
 
-	// game keep going, switch handle to the next player
-	g.State.Player2Turn = !g.State.Player2Turn
-	return nil
+// add adds the move to the running sums of its row, column and diagonals
+func (rs *RunningSum) add(n int, row int, col int, move int) {
+	if rs.rowSum == nil {
+		rs.rowSum = make([]int, n)
+	}
+	if rs.columnSum == nil {
+		rs.columnSum = make([]int, n)
+	}
+	rs.rowSum[row] += move
+	rs.columnSum[col] += move
+	if row == col {
+		rs.diagonalSum += move
+	}
+	// reverse diagonal position pattern row = n - 1- column
+	if row == n-1-col {
+		rs.reverseDiagonalSum += move
+	}
 }
 
-func (g *Game) ShowGameState(sessionId string) string {
-	lineHeader := fmt.Sprintf("Session: %s. Player1: %s represent by X. Player2: %s represent by O.", sessionId, g.Player1Name, g.Player2Name)
-	lineBoard := ""
-	// m takes value 1, -1, or 0
+// winLength returns the number of marks in a row needed to win, a full row if not set
+func (g *Game) winLength() int {
+	if g.WinLength == 0 {
+		return len(g.Board)
+	}
+	return g.WinLength
+}
+
+// isWinningMove checks if the move at row, col completes a line of win length
+func (g *Game) isWinningMove(row int, col int) bool {
+	n := len(g.Board)
+	k := g.winLength()
+	// a full row is needed, the running sums tell us directly
+	if k == n {
+		return math.Abs(float64(g.runningSum.rowSum[row])) == float64(n) ||
+			math.Abs(float64(g.runningSum.columnSum[col])) == float64(n) ||
+			math.Abs(float64(g.runningSum.diagonalSum)) == float64(n) ||
+			math.Abs(float64(g.runningSum.reverseDiagonalSum)) == float64(n)
+	}
+	// otherwise count the consecutive marks through the move in each direction
+	directions := [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for _, d := range directions {
+		length := g.lineLength(row, col, d[0], d[1])
+		if length == k || (length > k && !g.ExactWinLength) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineLength counts consecutive marks equal to the mark at row, col along direction dr, dc in both ways
+func (g *Game) lineLength(row int, col int, dr int, dc int) int {
+	n := len(g.Board)
+	mark := g.Board[row][col]
+	count := 1
+	for r, c := row+dr, col+dc; r >= 0 && c >= 0 && r < n && c < n && g.Board[r][c] == mark; r, c = r+dr, c+dc {
+		count++
+	}
+	for r, c := row-dr, col-dc; r >= 0 && c >= 0 && r < n && c < n && g.Board[r][c] == mark; r, c = r-dr, c-dc {
+		count++
+	}
+	return count
+}
+
+// isBoardFull checks if all positions are filled
+func (g *Game) isBoardFull() bool {
 	for _, row := range g.Board {
-		lineBoard += "\n___________\n"
-		for _, col := range row {
-			if col == 1 {
-				lineBoard += "X"
-			} else if col == -1 {
-				lineBoard += "O"
-			} else {
-				lineBoard += " "
+		for _, mark := range row {
+			if mark == 0 {
+				return false
 			}
-			lineBoard += " | "
 		}
 	}
-	lineBoard += "\n___________\n"
+	return true
+}
+
+// ShowGameState renders the game as text for the session
+func (g *Game) ShowGameState(sessionId string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.showGameState(sessionId)
+}
+
+// showGameState the caller holds the lock
+func (g *Game) showGameState(sessionId string) string {
+	lineHeader := fmt.Sprintf("Session: %s. Variant: %s. Player1: %s represent by %s. Player2: %s represent by %s.",
+		sessionId, g.Rules().Name(), g.Player1Name, markSymbol(g.playerMark(false)), g.Player2Name, markSymbol(g.playerMark(true)))
+	lineBoard := g.Rules().Render(g)
 
 	var lineState = "Game state: "
 	if g.State.Draw {
@@ -197,30 +451,68 @@ func (g *Game) ShowGameState(sessionId string) string {
 		lineState += fmt.Sprintf("%s Won", g.Player1Name)
 	} else if g.State.Player2Won {
 		lineState += fmt.Sprintf("%s Won", g.Player2Name)
+	} else if g.State.End {
+		lineState += fmt.Sprintf("Abandoned")
 	} else if g.State.Player2Turn {
 		lineState += fmt.Sprintf("%s Turn", g.Player2Name)
 	} else {
 		lineState += fmt.Sprintf("%s Turn", g.Player1Name)
 	}
+	if g.inOpeningPlacement() {
+		lineState += fmt.Sprintf(". Opening: place %s", markSymbol(g.markToMove()))
+	} else if g.State.Phase == PhaseSwap2Choose {
+		lineState += fmt.Sprintf(". Opening: choose %s, %s or %s", ChooseX, ChooseO, ChoosePlaceTwo)
+	} else if g.State.Phase == PhaseSwap2ChooseColor {
+		lineState += fmt.Sprintf(". Opening: choose %s or %s", ChooseX, ChooseO)
+	}
+	if g.State.TakebackRequestedBy == 1 {
+		lineState += fmt.Sprintf(". Takeback requested by %s", g.Player1Name)
+	} else if g.State.TakebackRequestedBy == 2 {
+		lineState += fmt.Sprintf(". Takeback requested by %s", g.Player2Name)
+	}
+	if g.State.DrawOfferedBy == 1 {
+		lineState += fmt.Sprintf(". Draw offered by %s", g.Player1Name)
+	} else if g.State.DrawOfferedBy == 2 {
+		lineState += fmt.Sprintf(". Draw offered by %s", g.Player2Name)
+	}
+	if g.State.End && g.State.EndReason != EndReasonBoard {
+		lineState += fmt.Sprintf(". Ended by %s", g.State.EndReason)
+	}
+	if g.RematchGameId != "" {
+		lineState += fmt.Sprintf(". Rematch: %s", g.RematchGameId)
+	} else if g.RematchRequestedBy == 1 {
+		lineState += fmt.Sprintf(". Rematch requested by %s", g.Player1Name)
+	} else if g.RematchRequestedBy == 2 {
+		lineState += fmt.Sprintf(". Rematch requested by %s", g.Player2Name)
+	}
+	if g.TimeControl != nil {
+		clocks := g.remaining(time.Now())
+		lineState += fmt.Sprintf(". Time left: %s %s, %s %s", g.Player1Name, clocks[0].Round(time.Second/10), g.Player2Name, clocks[1].Round(time.Second/10))
+	}
 
-	x := fmt.Sprintf("%s\n%s\n%s", lineHeader, lineBoard, lineState)
-	fmt.Println(x)
 	return fmt.Sprintf("%s\n%s\n%s", lineHeader, lineBoard, lineState)
 }
 
-// EndGame end the game
-func (g *Game) EndGame(gameId string, playerId string) error {
-	if gameId != g.Id {
-		return GameIdNotfoundErr
+// bump increases the version and wakes up everyone waiting for a change, the caller holds the lock
+func (g *Game) bump() {
+	g.Version++
+	if g.changed != nil {
+		close(g.changed)
+		g.changed = nil
 	}
-	if playerId == "" || (playerId != g.Player1Id && playerId != g.Player2Id) {
-		return InvalidPlayerIdErr
+}
+
+// Changed returns a channel closed once the version of the game moves past since, closed right away if it already has
+func (g *Game) Changed(since int) <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.Version != since {
+		ch := make(chan struct{})
+		close(ch)
+		return ch
 	}
-	g.State.End = true
-	g.State.EndTime = time.Now()
-	// set a tie if no one wins for simplicity
-	if !g.State.Player1Won && !g.State.Player2Won {
-		g.State.Draw = true
+	if g.changed == nil {
+		g.changed = make(chan struct{})
 	}
-	return nil
+	return g.changed
 }
@@ -14,7 +14,7 @@ func TestGame_EndGame(t *testing.T) {
 		Player1Name string
 		Player2Id   string
 		Player2Name string
-		Board       [3][3]int
+		Board       [][]int
 		State       State
 		runningSum  RunningSum
 		mu          *sync.Mutex
@@ -77,7 +77,7 @@ func TestGame_EndGame(t *testing.T) {
 				Board:       tt.fields.Board,
 				State:       tt.fields.State,
 				runningSum:  tt.fields.runningSum,
-				mu:          tt.fields.mu,
+				mu:          &sync.Mutex{},
 			}
 			if err := g.EndGame(tt.args.gameId, tt.args.playerId); (err != nil) != tt.wantErr {
 				t.Errorf("EndGame() error = %v, wantErr %v", err, tt.wantErr)
@@ -93,7 +93,7 @@ func TestGame_Join(t *testing.T) {
 		Player1Name string
 		Player2Id   string
 		Player2Name string
-		Board       [3][3]int
+		Board       [][]int
 		State       State
 		runningSum  RunningSum
 		mu          *sync.Mutex
@@ -186,7 +186,7 @@ func TestGame_Join(t *testing.T) {
 				Board:       tt.fields.Board,
 				State:       tt.fields.State,
 				runningSum:  tt.fields.runningSum,
-				mu:          tt.fields.mu,
+				mu:          &sync.Mutex{},
 			}
 			err := g.Join(tt.args.gameId, tt.args.playerId, tt.args.playerName)
 			if (err != nil) != tt.wantErr {
@@ -206,7 +206,7 @@ func TestGame_Move_FailCases(t *testing.T) {
 		Player1Name string
 		Player2Id   string
 		Player2Name string
-		Board       [3][3]int
+		Board       [][]int
 		State       State
 		runningSum  RunningSum
 		mu          *sync.Mutex
@@ -383,7 +383,7 @@ func TestGame_Move_FailCases(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{1, -1, 1}, {1, -1, 0}, {1, -1, 1},
 				},
 				mu: &sync.Mutex{},
@@ -428,7 +428,8 @@ func TestGame_GeneralMove(t *testing.T) {
 		Player1Name string
 		Player2Id   string
 		Player2Name string
-		Board       [3][3]int
+		Board       [][]int
+		WinLength   int
 		State       State
 		runningSum  RunningSum
 		mu          *sync.Mutex
@@ -453,7 +454,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{0, 0, 1}, {1, -1, 0}, {1, -1, 1},
 				},
 				mu: &sync.Mutex{},
@@ -470,7 +471,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{1, 0, 1}, {1, -1, 0}, {1, -1, 1},
 				},
 				State: State{
@@ -490,11 +491,11 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{0, 1, 1}, {1, -1, 0}, {1, -1, 1},
 				},
 				runningSum: RunningSum{
-					rowSum: [3]int{2, 0, 0},
+					rowSum: []int{2, 0, 0},
 				},
 				mu: &sync.Mutex{},
 			},
@@ -510,7 +511,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{1, 1, 1}, {1, -1, 0}, {1, -1, 1},
 				},
 				State: State{
@@ -519,6 +520,7 @@ func TestGame_GeneralMove(t *testing.T) {
 					Player1Won:  true,
 					Player2Won:  false,
 					Draw:        false,
+					EndReason:   EndReasonBoard,
 				},
 			},
 		},
@@ -530,12 +532,12 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{0, 0, 1}, {1, -1, 0}, {1, -1, 1},
 				},
 				runningSum: RunningSum{
-					rowSum:    [3]int{0, 0, 0},
-					columnSum: [3]int{2, 0, 0},
+					rowSum:    []int{0, 0, 0},
+					columnSum: []int{2, 0, 0},
 				},
 				mu: &sync.Mutex{},
 			},
@@ -551,7 +553,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{1, 0, 1}, {1, -1, 0}, {1, -1, 1},
 				},
 				State: State{
@@ -560,6 +562,7 @@ func TestGame_GeneralMove(t *testing.T) {
 					Player1Won:  true,
 					Player2Won:  false,
 					Draw:        false,
+					EndReason:   EndReasonBoard,
 				},
 			},
 		},
@@ -571,12 +574,12 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{0, 0, 1}, {-1, 1, 0}, {0, -1, 1},
 				},
 				runningSum: RunningSum{
-					rowSum:      [3]int{0, 0, 0},
-					columnSum:   [3]int{0, 0, 0},
+					rowSum:      []int{0, 0, 0},
+					columnSum:   []int{0, 0, 0},
 					diagonalSum: 2,
 				},
 				mu: &sync.Mutex{},
@@ -593,7 +596,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{1, 0, 1}, {-1, 1, 0}, {0, -1, 1},
 				},
 				State: State{
@@ -602,6 +605,7 @@ func TestGame_GeneralMove(t *testing.T) {
 					Player1Won:  true,
 					Player2Won:  false,
 					Draw:        false,
+					EndReason:   EndReasonBoard,
 				},
 			},
 		},
@@ -613,12 +617,12 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{-1, 0, 0}, {-1, 1, 0}, {1, -1, 1},
 				},
 				runningSum: RunningSum{
-					rowSum:             [3]int{0, 0, 0},
-					columnSum:          [3]int{0, 0, 0},
+					rowSum:             []int{0, 0, 0},
+					columnSum:          []int{0, 0, 0},
 					diagonalSum:        0,
 					reverseDiagonalSum: 2,
 				},
@@ -636,7 +640,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{-1, 0, 1}, {-1, 1, 0}, {1, -1, 1},
 				},
 				State: State{
@@ -645,6 +649,7 @@ func TestGame_GeneralMove(t *testing.T) {
 					Player1Won:  true,
 					Player2Won:  false,
 					Draw:        false,
+					EndReason:   EndReasonBoard,
 				},
 			},
 		},
@@ -656,7 +661,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{-1, 1, 0}, {-1, 1, 1}, {1, -1, -1},
 				},
 				mu: &sync.Mutex{},
@@ -673,7 +678,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{-1, 1, 1}, {-1, 1, 1}, {1, -1, -1},
 				},
 				State: State{
@@ -682,6 +687,7 @@ func TestGame_GeneralMove(t *testing.T) {
 					Player1Won:  false,
 					Player2Won:  false,
 					Draw:        true,
+					EndReason:   EndReasonBoard,
 				},
 			},
 		},
@@ -693,12 +699,12 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{-1, -1, 0}, {-1, 0, 0}, {1, 0, 0},
 				},
 				runningSum: RunningSum{
-					rowSum:             [3]int{-2, 0, 0},
-					columnSum:          [3]int{0, 0, 0},
+					rowSum:             []int{-2, 0, 0},
+					columnSum:          []int{0, 0, 0},
 					diagonalSum:        0,
 					reverseDiagonalSum: 0,
 				},
@@ -717,7 +723,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{-1, -1, -1}, {-1, 0, 0}, {1, 0, 0},
 				},
 				State: State{
@@ -726,6 +732,7 @@ func TestGame_GeneralMove(t *testing.T) {
 					Player1Won:  false,
 					Player2Won:  true,
 					Draw:        false,
+					EndReason:   EndReasonBoard,
 				},
 			},
 		},
@@ -737,12 +744,12 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{1, 0, -1}, {0, 0, -1}, {1, 0, 0},
 				},
 				runningSum: RunningSum{
-					rowSum:             [3]int{0, 0, 0},
-					columnSum:          [3]int{0, 0, -2},
+					rowSum:             []int{0, 0, 0},
+					columnSum:          []int{0, 0, -2},
 					diagonalSum:        0,
 					reverseDiagonalSum: 0,
 				},
@@ -761,7 +768,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{1, 0, -1}, {0, 0, -1}, {1, 0, -1},
 				},
 				State: State{
@@ -770,6 +777,7 @@ func TestGame_GeneralMove(t *testing.T) {
 					Player1Won:  false,
 					Player2Won:  true,
 					Draw:        false,
+					EndReason:   EndReasonBoard,
 				},
 			},
 		},
@@ -781,12 +789,12 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{-1, 0, 1}, {0, -1, 1}, {1, 0, 0},
 				},
 				runningSum: RunningSum{
-					rowSum:             [3]int{0, 0, 0},
-					columnSum:          [3]int{0, 0, 0},
+					rowSum:             []int{0, 0, 0},
+					columnSum:          []int{0, 0, 0},
 					diagonalSum:        -2,
 					reverseDiagonalSum: 0,
 				},
@@ -805,7 +813,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{-1, 0, 1}, {0, -1, 1}, {1, 0, -1},
 				},
 				State: State{
@@ -814,6 +822,7 @@ func TestGame_GeneralMove(t *testing.T) {
 					Player1Won:  false,
 					Player2Won:  true,
 					Draw:        false,
+					EndReason:   EndReasonBoard,
 				},
 			},
 		},
@@ -825,12 +834,12 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{1, 0, -1}, {0, -1, 1}, {0, 0, 1},
 				},
 				runningSum: RunningSum{
-					rowSum:             [3]int{0, 0, 0},
-					columnSum:          [3]int{0, 0, 0},
+					rowSum:             []int{0, 0, 0},
+					columnSum:          []int{0, 0, 0},
 					diagonalSum:        0,
 					reverseDiagonalSum: -2,
 				},
@@ -849,7 +858,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player1Name: "bob",
 				Player2Id:   "test_player2_id",
 				Player2Name: "john",
-				Board: [3][3]int{
+				Board: [][]int{
 					{1, 0, -1}, {0, -1, 1}, {-1, 0, 1},
 				},
 				State: State{
@@ -858,6 +867,103 @@ func TestGame_GeneralMove(t *testing.T) {
 					Player1Won:  false,
 					Player2Won:  true,
 					Draw:        false,
+					EndReason:   EndReasonBoard,
+				},
+			},
+		},
+		{
+			name: "Player 1 four in a row win on 4 x 4 board",
+			fields: fields{
+				Id:          "test_game_id",
+				Player1Id:   "test_player1_id",
+				Player1Name: "bob",
+				Player2Id:   "test_player2_id",
+				Player2Name: "john",
+				Board: [][]int{
+					{1, 1, 1, 0}, {-1, -1, 0, 0}, {-1, 0, 0, 0}, {0, 0, 0, 0},
+				},
+				runningSum: RunningSum{
+					rowSum:    []int{3, -2, -1, 0},
+					columnSum: []int{-1, 0, 1, 0},
+				},
+				mu: &sync.Mutex{},
+			},
+			args: args{
+				playerId: "test_player1_id",
+				row:      0,
+				col:      3,
+			},
+			wantErr: false,
+			want: &Game{
+				Board: [][]int{
+					{1, 1, 1, 1}, {-1, -1, 0, 0}, {-1, 0, 0, 0}, {0, 0, 0, 0},
+				},
+				State: State{
+					End:        true,
+					Player1Won: true,
+					EndReason:  EndReasonBoard,
+				},
+			},
+		},
+		{
+			name: "Player 2 three in a row diagonal win on 5 x 5 board",
+			fields: fields{
+				Id:          "test_game_id",
+				Player1Id:   "test_player1_id",
+				Player1Name: "bob",
+				Player2Id:   "test_player2_id",
+				Player2Name: "john",
+				Board: [][]int{
+					{0, 0, 0, 0, 0}, {0, 1, 0, -1, 0}, {0, 1, -1, 0, 0}, {0, 0, 0, 0, 0}, {0, 0, 0, 0, 0},
+				},
+				WinLength: 3,
+				State:     State{Player2Turn: true},
+				mu:        &sync.Mutex{},
+			},
+			args: args{
+				playerId: "test_player2_id",
+				row:      3,
+				col:      1,
+			},
+			wantErr: false,
+			want: &Game{
+				Board: [][]int{
+					{0, 0, 0, 0, 0}, {0, 1, 0, -1, 0}, {0, 1, -1, 0, 0}, {0, -1, 0, 0, 0}, {0, 0, 0, 0, 0},
+				},
+				State: State{
+					Player2Turn: true,
+					End:         true,
+					Player2Won:  true,
+					EndReason:   EndReasonBoard,
+				},
+			},
+		},
+		{
+			name: "Two in a row does not win three in a row on 5 x 5 board",
+			fields: fields{
+				Id:          "test_game_id",
+				Player1Id:   "test_player1_id",
+				Player1Name: "bob",
+				Player2Id:   "test_player2_id",
+				Player2Name: "john",
+				Board: [][]int{
+					{0, 0, 0, 0, 0}, {0, 1, 0, 0, 0}, {0, 0, -1, 0, 0}, {0, 0, 0, 0, 0}, {0, 0, 0, 0, 0},
+				},
+				WinLength: 3,
+				mu:        &sync.Mutex{},
+			},
+			args: args{
+				playerId: "test_player1_id",
+				row:      1,
+				col:      2,
+			},
+			wantErr: false,
+			want: &Game{
+				Board: [][]int{
+					{0, 0, 0, 0, 0}, {0, 1, 1, 0, 0}, {0, 0, -1, 0, 0}, {0, 0, 0, 0, 0}, {0, 0, 0, 0, 0},
+				},
+				State: State{
+					Player2Turn: true,
 				},
 			},
 		},
@@ -871,6 +977,7 @@ func TestGame_GeneralMove(t *testing.T) {
 				Player2Id:   tt.fields.Player2Id,
 				Player2Name: tt.fields.Player2Name,
 				Board:       tt.fields.Board,
+				WinLength:   tt.fields.WinLength,
 				State:       tt.fields.State,
 				runningSum:  tt.fields.runningSum,
 				mu:          tt.fields.mu,
@@ -893,13 +1000,19 @@ func TestGame_GeneralMove(t *testing.T) {
 }
 
 func TestNewGameFactory_CreateGame(t *testing.T) {
+	type fields struct {
+		Size      int
+		WinLength int
+	}
 	type args struct {
 		playerName string
 	}
 	tests := []struct {
-		name string
-		args args
-		want *Game
+		name        string
+		fields      fields
+		args        args
+		want        *Game
+		wantErrType error
 	}{
 		{
 			name: "general",
@@ -908,13 +1021,60 @@ func TestNewGameFactory_CreateGame(t *testing.T) {
 			},
 			want: &Game{
 				Player1Name: "bob",
+				Board:       [][]int{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}},
+				WinLength:   3,
+			},
+		},
+		{
+			name: "4 x 4 board defaults to four in a row",
+			fields: fields{
+				Size: 4,
+			},
+			args: args{
+				playerName: "bob",
+			},
+			want: &Game{
+				Player1Name: "bob",
+				Board:       [][]int{{0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}},
+				WinLength:   4,
+			},
+		},
+		{
+			name: "InvalidBoardSizeErr",
+			fields: fields{
+				Size: 2,
+			},
+			args: args{
+				playerName: "bob",
+			},
+			wantErrType: InvalidBoardSizeErr,
+		},
+		{
+			name: "InvalidWinLengthErr",
+			fields: fields{
+				Size:      4,
+				WinLength: 5,
+			},
+			args: args{
+				playerName: "bob",
 			},
+			wantErrType: InvalidWinLengthErr,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gf := &NewGameFactory{}
-			got := gf.CreateGame(tt.args.playerName)
+			gf := &NewGameFactory{
+				Size:      tt.fields.Size,
+				WinLength: tt.fields.WinLength,
+			}
+			got, err := gf.CreateGame(tt.args.playerName)
+			if !errors.Is(err, tt.wantErrType) {
+				t.Errorf("CreateGame() error = %v, wantErr %v", err, tt.wantErrType)
+				return
+			}
+			if err != nil {
+				return
+			}
 			if got.Player1Name != tt.want.Player1Name {
 				t.Errorf("CreateGame() = %v, want %v", got, tt.want)
 			} else if got.Player1Id == "" {
@@ -923,6 +1083,80 @@ func TestNewGameFactory_CreateGame(t *testing.T) {
 				t.Error("expect empty player2 id and name")
 			} else if !reflect.DeepEqual(got.State, tt.want.State) {
 				t.Errorf("got state %v, want %v", got.State, tt.want.State)
+			} else if !reflect.DeepEqual(got.Board, tt.want.Board) || got.WinLength != tt.want.WinLength {
+				t.Errorf("got board %v win length %d, want %v win length %d", got.Board, got.WinLength, tt.want.Board, tt.want.WinLength)
+			}
+		})
+	}
+}
+
+func TestGame_Changed(t *testing.T) {
+	g, err := (&NewGameFactory{}).CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	changed := g.Changed(0)
+	select {
+	case <-changed:
+		t.Fatal("expect no change before the game changed")
+	default:
+	}
+	if err := g.Join(g.Id, "test_player2_id", "john"); err != nil {
+		t.Fatalf("Join() unexpected error %v", err)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatal("expect the join to be notified")
+	}
+
+	changes := []struct {
+		name   string
+		change func() error
+	}{
+		{"move", func() error { return g.Move(g.Player1Id, 0, 0) }},
+		{"takeback request", func() error { return g.RequestTakeback(g.Player1Id) }},
+		{"takeback accepted", func() error { return g.RespondTakeback(g.Player2Id, true) }},
+		{"end", func() error { return g.EndGame(g.Id, g.Player1Id) }},
+	}
+	for i, c := range changes {
+		version := g.Version
+		if err := c.change(); err != nil {
+			t.Fatalf("%s unexpected error %v", c.name, err)
+		}
+		if g.Version <= version {
+			t.Errorf("%s kept version %d", c.name, g.Version)
+		}
+		select {
+		case <-g.Changed(version):
+		default:
+			t.Errorf("change %d %s not notified", i, c.name)
+		}
+	}
+}
+
+func TestGame_JoinAsAccount(t *testing.T) {
+	gf := &NewGameFactory{AccountId: "alice_account"}
+	tests := []struct {
+		name      string
+		accountId string
+		wantErr   error
+	}{
+		{name: "same account", accountId: "alice_account", wantErr: AlreadyJoinGameErr},
+		{name: "guest", accountId: ""},
+		{name: "another account", accountId: "bob_account"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := gf.CreateGame("alice")
+			if err != nil {
+				t.Fatalf("CreateGame() unexpected error %v", err)
+			}
+			if err := g.JoinAsAccount(g.Id, "test_player2_id", "bob", tt.accountId); err != tt.wantErr {
+				t.Fatalf("JoinAsAccount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (g.Player1AccountId != "alice_account" || g.Player2AccountId != tt.accountId) {
+				t.Errorf("expect accounts alice_account and %q, got %q and %q", tt.accountId, g.Player1AccountId, g.Player2AccountId)
 			}
 		})
 	}
@@ -0,0 +1,174 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+func init() {
+	RegisterRules(Gomoku{})
+}
+
+// gomoku board limits, X plays black and moves first, O plays white
+const (
+	DefaultGomokuSize = 15
+	MinGomokuSize     = 7
+	GomokuWinLength   = 5
+)
+
+// Swap2Opening name of the swap2 opening protocol
+const Swap2Opening = "swap2"
+
+// Phase opening phase of a game, empty once normal play started
+type Phase string
+
+// swap2 opening phases
+const (
+	// PhaseSwap2PlaceThree player 1 places X, O, X
+	PhaseSwap2PlaceThree Phase = "swap2-place-three"
+	// PhaseSwap2Choose player 2 chooses to play X, to play O or to place two more stones
+	PhaseSwap2Choose Phase = "swap2-choose"
+	// PhaseSwap2PlaceTwo player 2 places O, X
+	PhaseSwap2PlaceTwo Phase = "swap2-place-two"
+	// PhaseSwap2ChooseColor player 1 chooses to play X or O
+	PhaseSwap2ChooseColor Phase = "swap2-choose-color"
+)
+
+// ColorChoice choices available during the swap2 opening
+type ColorChoice string
+
+const (
+	ChooseX        ColorChoice = "X"
+	ChooseO        ColorChoice = "O"
+	ChoosePlaceTwo ColorChoice = "place-two"
+)
+
+// predefined errors
+
+var (
+	InvalidOpeningErr     = fmt.Errorf("invalid opening. only %q is supported by the gomoku variant", Swap2Opening)
+	ColorChoicePendingErr = errors.New("invalid move. a color has to be chosen before the next stone is placed")
+	NoColorChoiceErr      = errors.New("no color choice pending in this game")
+	InvalidColorChoiceErr = fmt.Errorf("invalid color choice. choices: %q, %q or %q before the two extra stones are placed", ChooseX, ChooseO, ChoosePlaceTwo)
+)
+
+// Gomoku free-style five in a row on a 15 x 15 board. X moves first, overlines of six or more count
+// unless the game requires exactly five, and the game can start with the swap2 opening
+type Gomoku struct {
+	Classic
+}
+
+func (Gomoku) Name() string {
+	return "gomoku"
+}
+
+func (Gomoku) Configure(size int, winLength int) (int, int, error) {
+	if size == 0 {
+		size = DefaultGomokuSize
+	}
+	if size < MinGomokuSize || size > MaxBoardSize {
+		return 0, 0, fmt.Errorf("%w. gomoku is played on boards from %d x %d to %d x %d", InvalidBoardSizeErr, MinGomokuSize, MinGomokuSize, MaxBoardSize, MaxBoardSize)
+	}
+	if winLength != 0 && winLength != GomokuWinLength {
+		return 0, 0, fmt.Errorf("%w. gomoku is won with %d in a row", InvalidWinLengthErr, GomokuWinLength)
+	}
+	return size, GomokuWinLength, nil
+}
+
+// ChooseColor makes the color choice of the swap2 opening for the player whose turn it is
+func (g *Game) ChooseColor(playerId string, choice ColorChoice) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.State.End {
+		return GameAlreadyFinishedErr
+	}
+	if playerId != g.Player1Id && playerId != g.Player2Id {
+		return InvalidPlayerIdErr
+	}
+	if g.State.Phase != PhaseSwap2Choose && g.State.Phase != PhaseSwap2ChooseColor {
+		return NoColorChoiceErr
+	}
+	if (g.State.Player2Turn && playerId != g.Player2Id) || (!g.State.Player2Turn && playerId != g.Player1Id) {
+		return AnotherPlayerMoveTurnErr
+	}
+	if err := g.checkFlag(time.Now()); err != nil {
+		return err
+	}
+	if g.State.TakebackRequestedBy != 0 {
+		return TakebackPendingErr
+	}
+	player2 := g.State.Player2Turn
+	if err := g.chooseColor(choice); err != nil {
+		return err
+	}
+	g.record(player2, 0, 0, choice)
+	return nil
+}
+
+// chooseColor makes the color choice for the player to move, the caller holds the lock
+func (g *Game) chooseColor(choice ColorChoice) error {
+	switch {
+	case choice == ChoosePlaceTwo && g.State.Phase == PhaseSwap2Choose:
+		g.State.Phase = PhaseSwap2PlaceTwo
+		return nil
+	case choice == ChooseX || choice == ChooseO:
+		// the chooser takes the color, the other player gets the other one
+		chooserMark := 1
+		if choice == ChooseO {
+			chooserMark = -1
+		}
+		g.State.ColorsSwapped = (g.State.Player2Turn && chooserMark == 1) || (!g.State.Player2Turn && chooserMark == -1)
+		g.State.Phase = ""
+		// normal play goes on with whoever holds the color to move
+		g.State.Player2Turn = g.playerMark(true) == g.markToMove()
+		return nil
+	default:
+		return InvalidColorChoiceErr
+	}
+}
+
+// inOpeningPlacement checks if the player to move is placing stones of both colors in the opening
+func (g *Game) inOpeningPlacement() bool {
+	return g.State.Phase == PhaseSwap2PlaceThree || g.State.Phase == PhaseSwap2PlaceTwo
+}
+
+// inOpeningChoice checks if the game waits for a color choice
+func (g *Game) inOpeningChoice() bool {
+	return g.State.Phase == PhaseSwap2Choose || g.State.Phase == PhaseSwap2ChooseColor
+}
+
+// advanceOpening moves to the color choice once the stones of the current opening phase are placed,
+// the other player chooses. the opener is player 1 unless the players swapped sides for a rematch
+func (g *Game) advanceOpening() {
+	stones := g.stoneCount()
+	if (g.State.Phase == PhaseSwap2PlaceThree && stones == 3) || (g.State.Phase == PhaseSwap2PlaceTwo && stones == 5) {
+		if g.State.Phase == PhaseSwap2PlaceThree {
+			g.State.Phase = PhaseSwap2Choose
+		} else {
+			g.State.Phase = PhaseSwap2ChooseColor
+		}
+		g.State.Player2Turn = !g.State.Player2Turn
+	}
+}
+
+// markToMove X and O alternate from an empty board with X first, so the stone count tells which mark is next
+func (g *Game) markToMove() int {
+	if g.stoneCount()%2 == 0 {
+		return 1
+	}
+	return -1
+}
+
+// stoneCount returns the number of filled positions
+func (g *Game) stoneCount() int {
+	var count int
+	for _, row := range g.Board {
+		for _, mark := range row {
+			if mark != 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
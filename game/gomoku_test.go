@@ -0,0 +1,177 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestGomokuGame(t *testing.T, gf *NewGameFactory) *Game {
+	gf.Variant = "gomoku"
+	g, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	if err := g.Join(g.Id, "test_player2_id", "john"); err != nil {
+		t.Fatalf("Join() unexpected error %v", err)
+	}
+	return g
+}
+
+func TestNewGameFactory_CreateGomokuGame(t *testing.T) {
+	tests := []struct {
+		name        string
+		gf          *NewGameFactory
+		wantSize    int
+		wantPhase   Phase
+		wantErrType error
+	}{
+		{
+			name:     "defaults to 15 x 15",
+			gf:       &NewGameFactory{Variant: "gomoku"},
+			wantSize: 15,
+		},
+		{
+			name:      "swap2 opening",
+			gf:        &NewGameFactory{Variant: "gomoku", Size: 19, Opening: Swap2Opening},
+			wantSize:  19,
+			wantPhase: PhaseSwap2PlaceThree,
+		},
+		{
+			name:        "InvalidWinLengthErr",
+			gf:          &NewGameFactory{Variant: "gomoku", WinLength: 4},
+			wantErrType: InvalidWinLengthErr,
+		},
+		{
+			name:        "InvalidOpeningErr for classic",
+			gf:          &NewGameFactory{Opening: Swap2Opening},
+			wantErrType: InvalidOpeningErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.gf.CreateGame("bob")
+			if !errors.Is(err, tt.wantErrType) {
+				t.Errorf("CreateGame() error = %v, wantErr %v", err, tt.wantErrType)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if len(got.Board) != tt.wantSize || got.WinLength != GomokuWinLength || got.State.Phase != tt.wantPhase {
+				t.Errorf("CreateGame() size %d win length %d phase %q, want %d, %d, %q", len(got.Board), got.WinLength, got.State.Phase, tt.wantSize, GomokuWinLength, tt.wantPhase)
+			}
+		})
+	}
+}
+
+func TestGame_GomokuOverline(t *testing.T) {
+	tests := []struct {
+		name           string
+		exactWinLength bool
+		wantEnd        bool
+	}{
+		{
+			name:    "overline wins in free-style",
+			wantEnd: true,
+		},
+		{
+			name:           "overline does not win with exactly five",
+			exactWinLength: true,
+			wantEnd:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGomokuGame(t, &NewGameFactory{ExactWinLength: tt.exactWinLength})
+			// X X X _ X X on row 7, filling the gap makes six in a row
+			for _, col := range []int{2, 3, 4, 6, 7} {
+				g.Board[7][col] = 1
+			}
+			if err := g.Move(g.Player1Id, 7, 5); err != nil {
+				t.Fatalf("Move() unexpected error %v", err)
+			}
+			if g.State.End != tt.wantEnd || g.State.Player1Won != tt.wantEnd {
+				t.Errorf("Move() state = %v, want end %v", g.State, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestGame_Swap2Opening(t *testing.T) {
+	tests := []struct {
+		name            string
+		choices         []ColorChoice
+		wantSwapped     bool
+		wantPlayer2Turn bool
+	}{
+		{
+			name:            "player 2 takes O and places the fourth stone",
+			choices:         []ColorChoice{ChooseO},
+			wantSwapped:     false,
+			wantPlayer2Turn: true,
+		},
+		{
+			name:            "player 2 takes X and player 1 places the fourth stone as O",
+			choices:         []ColorChoice{ChooseX},
+			wantSwapped:     true,
+			wantPlayer2Turn: false,
+		},
+		{
+			name:            "player 2 places two more and player 1 takes O",
+			choices:         []ColorChoice{ChoosePlaceTwo, ChooseO},
+			wantSwapped:     true,
+			wantPlayer2Turn: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGomokuGame(t, &NewGameFactory{Opening: Swap2Opening})
+			// player 1 places X, O, X
+			for i, col := range []int{0, 1, 2} {
+				if err := g.Move(g.Player1Id, 0, col); err != nil {
+					t.Fatalf("Move() unexpected error %v", err)
+				}
+				if want := []int{1, -1, 1}[i]; g.Board[0][col] != want {
+					t.Errorf("opening stone %d = %d, want %d", i, g.Board[0][col], want)
+				}
+			}
+			if err := g.Move(g.Player2Id, 1, 0); !errors.Is(err, ColorChoicePendingErr) {
+				t.Errorf("Move() error = %v, wantErr %v", err, ColorChoicePendingErr)
+			}
+			if err := g.ChooseColor(g.Player1Id, ChooseX); !errors.Is(err, AnotherPlayerMoveTurnErr) {
+				t.Errorf("ChooseColor() error = %v, wantErr %v", err, AnotherPlayerMoveTurnErr)
+			}
+			if err := g.ChooseColor(g.Player2Id, tt.choices[0]); err != nil {
+				t.Fatalf("ChooseColor() unexpected error %v", err)
+			}
+			if len(tt.choices) > 1 {
+				// player 2 places O, X then player 1 chooses
+				for _, col := range []int{3, 4} {
+					if err := g.Move(g.Player2Id, 0, col); err != nil {
+						t.Fatalf("Move() unexpected error %v", err)
+					}
+				}
+				if g.Board[0][3] != -1 || g.Board[0][4] != 1 {
+					t.Errorf("expect O, X placed by player 2, board row %v", g.Board[0])
+				}
+				if err := g.ChooseColor(g.Player1Id, tt.choices[1]); err != nil {
+					t.Fatalf("ChooseColor() unexpected error %v", err)
+				}
+			}
+			if g.State.Phase != "" || g.State.ColorsSwapped != tt.wantSwapped || g.State.Player2Turn != tt.wantPlayer2Turn {
+				t.Errorf("state after opening = %+v, want swapped %v player 2 turn %v", g.State, tt.wantSwapped, tt.wantPlayer2Turn)
+			}
+			// next stone is O played by whoever holds O
+			playerId := g.Player1Id
+			if g.State.Player2Turn {
+				playerId = g.Player2Id
+			}
+			if err := g.Move(playerId, 5, 5); err != nil {
+				t.Fatalf("Move() unexpected error %v", err)
+			}
+			if g.Board[5][5] != -1 {
+				t.Errorf("expect O after the opening, got %d", g.Board[5][5])
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MoveRecord an entry of the game history, a placed mark or a color choice of the swap2 opening
+type MoveRecord struct {
+	// Ply 1 based number of the entry in the game history
+	Ply int `json:"ply"`
+	// Player 1 or 2
+	Player int `json:"player"`
+	// Mark X or O placed by the move, empty for a color choice
+	Mark   string `json:"mark,omitempty"`
+	Row    int    `json:"row"`
+	Column int    `json:"column"`
+	// Choice color choice made instead of a move during the swap2 opening
+	Choice ColorChoice `json:"choice,omitempty"`
+	Time   time.Time   `json:"time"`
+}
+
+// InvalidPlyErr predefined error for replays out of the history
+var InvalidPlyErr = fmt.Errorf("invalid ply. constraints: 0 <= ply <= number of moves")
+
+// record appends the move just played by the player to the history, hands the clock over and bumps the version,
+// the caller holds the lock
+func (g *Game) record(player2 bool, row int, col int, choice ColorChoice) {
+	r := MoveRecord{
+		Ply:    len(g.Moves) + 1,
+		Player: 1,
+		Row:    row,
+		Column: col,
+		Choice: choice,
+		Time:   time.Now(),
+	}
+	if player2 {
+		r.Player = 2
+	}
+	if choice == "" {
+		r.Mark = markSymbol(g.Board[row][col])
+	}
+	g.Moves = append(g.Moves, r)
+	// a move declines the opponent's draw offer
+	if g.State.DrawOfferedBy != 0 && g.State.DrawOfferedBy != r.Player {
+		g.State.DrawOfferedBy = 0
+	}
+	g.switchClock(player2, r.Time)
+	g.bump()
+}
+
+// History returns a copy of the moves played so far
+func (g *Game) History() []MoveRecord {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]MoveRecord{}, g.Moves...)
+}
+
+// Replay returns a copy of the game as it was after the given number of plies, ply 0 is the starting position
+func (g *Game) Replay(ply int) (*Game, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if ply < 0 || ply > len(g.Moves) {
+		return nil, InvalidPlyErr
+	}
+	return g.replay(g.Moves[:ply])
+}
+
+// replay rebuilds the game from its starting position by playing the moves again, the caller holds the lock
+func (g *Game) replay(moves []MoveRecord) (*Game, error) {
+	r := &Game{
+		Id:               g.Id,
+		Player1Id:        g.Player1Id,
+		Player1Name:      g.Player1Name,
+		Player2Id:        g.Player2Id,
+		Player2Name:      g.Player2Name,
+		Player1AccountId: g.Player1AccountId,
+		Player2AccountId: g.Player2AccountId,
+		Board:            newBoard(len(g.Board)),
+		WinLength:        g.WinLength,
+		ExactWinLength:   g.ExactWinLength,
+		State:            g.initialState,
+		initialState:     g.initialState,
+		rules:            g.rules,
+		mu:               &sync.Mutex{},
+	}
+	for _, m := range moves {
+		var err error
+		if m.Choice != "" {
+			err = r.chooseColor(m.Choice)
+		} else {
+			err = r.play(m.Row, m.Column)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("replay ply %d: %w", m.Ply, err)
+		}
+		r.Moves = append(r.Moves, m)
+	}
+	return r, nil
+}
@@ -0,0 +1,111 @@
+package game
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGame_History(t *testing.T) {
+	g := newTestUltimateGame(t)
+	moves := []struct {
+		playerId string
+		row      int
+		col      int
+	}{
+		{g.Player1Id, 4, 4},
+		{g.Player2Id, 4, 3},
+		{g.Player1Id, 4, 0},
+	}
+	for _, m := range moves {
+		if err := g.Move(m.playerId, m.row, m.col); err != nil {
+			t.Fatalf("Move() unexpected error %v", err)
+		}
+	}
+	history := g.History()
+	if len(history) != 3 {
+		t.Fatalf("History() returns %d moves, want 3", len(history))
+	}
+	for i, r := range history {
+		wantPlayer, wantMark := 1, "X"
+		if i%2 == 1 {
+			wantPlayer, wantMark = 2, "O"
+		}
+		if r.Ply != i+1 || r.Player != wantPlayer || r.Mark != wantMark || r.Row != moves[i].row || r.Column != moves[i].col || r.Time.IsZero() {
+			t.Errorf("History()[%d] = %+v", i, r)
+		}
+	}
+}
+
+func TestGame_Replay(t *testing.T) {
+	g := newTestUltimateGame(t)
+	for _, m := range []Position{{Row: 4, Column: 4}, {Row: 4, Column: 3}, {Row: 4, Column: 0}} {
+		playerId := g.Player1Id
+		if g.State.Player2Turn {
+			playerId = g.Player2Id
+		}
+		if err := g.Move(playerId, m.Row, m.Column); err != nil {
+			t.Fatalf("Move() unexpected error %v", err)
+		}
+	}
+	tests := []struct {
+		name        string
+		ply         int
+		wantStones  int
+		wantNext    *Position
+		wantErrType error
+	}{
+		{name: "starting position", ply: 0, wantStones: 0},
+		{name: "after the first move", ply: 1, wantStones: 1, wantNext: &Position{Row: 1, Column: 1}},
+		{name: "latest position", ply: 3, wantStones: 3, wantNext: &Position{Row: 1, Column: 0}},
+		{name: "InvalidPlyErr", ply: 4, wantErrType: InvalidPlyErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.Replay(tt.ply)
+			if !errors.Is(err, tt.wantErrType) {
+				t.Errorf("Replay() error = %v, wantErr %v", err, tt.wantErrType)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got.stoneCount() != tt.wantStones || len(got.Moves) != tt.ply {
+				t.Errorf("Replay() stones %d moves %d, want %d", got.stoneCount(), len(got.Moves), tt.wantStones)
+			}
+			var next *Position
+			if got.MetaBoard != nil {
+				next = got.MetaBoard.Next
+			}
+			if !reflect.DeepEqual(next, tt.wantNext) {
+				t.Errorf("Replay() next sub-board %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+	latest, _ := g.Replay(3)
+	if !reflect.DeepEqual(latest.Board, g.Board) || latest.State.Player2Turn != g.State.Player2Turn {
+		t.Errorf("Replay() of all moves does not match the game")
+	}
+}
+
+func TestGame_ReplaySwap2(t *testing.T) {
+	g := newTestGomokuGame(t, &NewGameFactory{Opening: Swap2Opening})
+	for _, col := range []int{0, 1, 2} {
+		if err := g.Move(g.Player1Id, 0, col); err != nil {
+			t.Fatalf("Move() unexpected error %v", err)
+		}
+	}
+	if err := g.ChooseColor(g.Player2Id, ChooseX); err != nil {
+		t.Fatalf("ChooseColor() unexpected error %v", err)
+	}
+	if err := g.Move(g.Player1Id, 5, 5); err != nil {
+		t.Fatalf("Move() unexpected error %v", err)
+	}
+	got, err := g.Replay(5)
+	if err != nil {
+		t.Fatalf("Replay() unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(got.State, g.State) || !reflect.DeepEqual(got.Board, g.Board) {
+		t.Errorf("Replay() state %+v, want %+v", got.State, g.State)
+	}
+}
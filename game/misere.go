@@ -0,0 +1,26 @@
+package game
+
+func init() {
+	RegisterRules(Misere{})
+}
+
+// Misere anti tic-tac-toe, the player who completes win length in a row loses. Board, legal moves and
+// win detection are classic, only the outcome is handed to the opponent
+type Misere struct {
+	Classic
+}
+
+func (Misere) Name() string {
+	return "misere"
+}
+
+// CompletedLineLoses the completed line is the line of the loser
+func (Misere) CompletedLineLoses() bool {
+	return true
+}
+
+// Terminal completing a line loses, so the opponent of the mover is the winner. a full board is still a draw
+func (m Misere) Terminal(g *Game, row int, col int) (bool, int) {
+	end, winner := m.Classic.Terminal(g, row, col)
+	return end, -winner
+}
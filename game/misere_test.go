@@ -0,0 +1,205 @@
+package game
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestGame_MisereMove(t *testing.T) {
+	type fields struct {
+		Id          string
+		Player1Id   string
+		Player1Name string
+		Player2Id   string
+		Player2Name string
+		Board       [][]int
+		State       State
+		runningSum  RunningSum
+		mu          *sync.Mutex
+	}
+	type args struct {
+		playerId string
+		row      int
+		col      int
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+		want    *Game
+	}{
+		{
+			name: "General Move turn switch to another user",
+			fields: fields{
+				Id:          "test_game_id",
+				Player1Id:   "test_player1_id",
+				Player1Name: "bob",
+				Player2Id:   "test_player2_id",
+				Player2Name: "john",
+				Board: [][]int{
+					{0, 0, 1}, {0, -1, 0}, {0, -1, 0},
+				},
+				mu: &sync.Mutex{},
+			},
+			args: args{
+				playerId: "test_player1_id",
+				row:      0,
+				col:      0,
+			},
+			wantErr: false,
+			want: &Game{
+				Board: [][]int{
+					{1, 0, 1}, {0, -1, 0}, {0, -1, 0},
+				},
+				State: State{
+					Player2Turn: true,
+				},
+			},
+		},
+		{
+			name: "Player 1 completes a row and player 2 wins",
+			fields: fields{
+				Id:          "test_game_id",
+				Player1Id:   "test_player1_id",
+				Player1Name: "bob",
+				Player2Id:   "test_player2_id",
+				Player2Name: "john",
+				Board: [][]int{
+					{0, 1, 1}, {-1, -1, 0}, {0, 0, 0},
+				},
+				runningSum: RunningSum{
+					rowSum: []int{2, -2, 0},
+				},
+				mu: &sync.Mutex{},
+			},
+			args: args{
+				playerId: "test_player1_id",
+				row:      0,
+				col:      0,
+			},
+			wantErr: false,
+			want: &Game{
+				Board: [][]int{
+					{1, 1, 1}, {-1, -1, 0}, {0, 0, 0},
+				},
+				State: State{
+					Player2Turn: false,
+					End:         true,
+					Player1Won:  false,
+					Player2Won:  true,
+					Draw:        false,
+					EndReason:   EndReasonBoard,
+				},
+			},
+		},
+		{
+			name: "Player 2 completes a diagonal and player 1 wins",
+			fields: fields{
+				Id:          "test_game_id",
+				Player1Id:   "test_player1_id",
+				Player1Name: "bob",
+				Player2Id:   "test_player2_id",
+				Player2Name: "john",
+				Board: [][]int{
+					{-1, 1, 0}, {1, -1, 0}, {1, 0, 0},
+				},
+				runningSum: RunningSum{
+					diagonalSum: -2,
+				},
+				State: State{Player2Turn: true},
+				mu:    &sync.Mutex{},
+			},
+			args: args{
+				playerId: "test_player2_id",
+				row:      2,
+				col:      2,
+			},
+			wantErr: false,
+			want: &Game{
+				Board: [][]int{
+					{-1, 1, 0}, {1, -1, 0}, {1, 0, -1},
+				},
+				State: State{
+					Player2Turn: true,
+					End:         true,
+					Player1Won:  true,
+					Player2Won:  false,
+					Draw:        false,
+					EndReason:   EndReasonBoard,
+				},
+			},
+		},
+		{
+			name: "Draw when all position filled without a line",
+			fields: fields{
+				Id:          "test_game_id",
+				Player1Id:   "test_player1_id",
+				Player1Name: "bob",
+				Player2Id:   "test_player2_id",
+				Player2Name: "john",
+				Board: [][]int{
+					{1, -1, 1}, {1, -1, -1}, {-1, 1, 0},
+				},
+				mu: &sync.Mutex{},
+			},
+			args: args{
+				playerId: "test_player1_id",
+				row:      2,
+				col:      2,
+			},
+			wantErr: false,
+			want: &Game{
+				Board: [][]int{
+					{1, -1, 1}, {1, -1, -1}, {-1, 1, 1},
+				},
+				State: State{
+					End:       true,
+					Draw:      true,
+					EndReason: EndReasonBoard,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{
+				Id:          tt.fields.Id,
+				Player1Id:   tt.fields.Player1Id,
+				Player1Name: tt.fields.Player1Name,
+				Player2Id:   tt.fields.Player2Id,
+				Player2Name: tt.fields.Player2Name,
+				Board:       tt.fields.Board,
+				State:       tt.fields.State,
+				runningSum:  tt.fields.runningSum,
+				rules:       Misere{},
+				mu:          tt.fields.mu,
+			}
+			err := g.Move(tt.args.playerId, tt.args.row, tt.args.col)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Move() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// set to same time for deep equal
+			tt.want.State.EndTime = g.State.EndTime
+
+			if !reflect.DeepEqual(g.State, tt.want.State) {
+				t.Errorf("Move() state mismatch actualState = %v, wantState %v", g.State, tt.want.State)
+			}
+			if !reflect.DeepEqual(g.Board, tt.want.Board) {
+				t.Errorf("Move() board mismatch actualBoard = %v, wantBoard %v", g.Board, tt.want.Board)
+			}
+		})
+	}
+}
+
+func TestNewGameFactory_CreateMisereGame(t *testing.T) {
+	gf := &NewGameFactory{Variant: "misere"}
+	got, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	if got.Rules().Name() != "misere" {
+		t.Errorf("CreateGame() rules = %s, want misere", got.Rules().Name())
+	}
+}
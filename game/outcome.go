@@ -0,0 +1,156 @@
+package game
+
+import (
+	"errors"
+	"time"
+)
+
+// EndReason how a game ended
+type EndReason string
+
+const (
+	// EndReasonBoard the game was won, lost or drawn on the board
+	EndReasonBoard EndReason = "board"
+	// EndReasonResign a player resigned, the opponent wins
+	EndReasonResign EndReason = "resign"
+	// EndReasonAgreement the players agreed to a draw
+	EndReasonAgreement EndReason = "agreement"
+	// EndReasonAbandon a player left the game, the opponent wins once the game started
+	EndReasonAbandon EndReason = "abandon"
+	// EndReasonTimeout the player to move ran out of time
+	EndReasonTimeout EndReason = "timeout"
+)
+
+// predefined errors
+
+var (
+	NoOpponentErr       = errors.New("no opponent joined yet. end the game instead")
+	DrawOfferPendingErr = errors.New("your draw offer is pending. please wait for the other player")
+	NoDrawOfferErr      = errors.New("no draw offer pending")
+	OwnDrawOfferErr     = errors.New("you can not answer your own draw offer. please wait for the other player")
+	BotDeclinesDrawErr  = errors.New("bots do not accept draw offers. please play on or resign")
+)
+
+// Resign ends the game with a win for the opponent
+func (g *Game) Resign(playerId string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.State.End {
+		return GameAlreadyFinishedErr
+	}
+	if playerId == "" || (playerId != g.Player1Id && playerId != g.Player2Id) {
+		return InvalidPlayerIdErr
+	}
+	if g.Player2Id == "" {
+		return NoOpponentErr
+	}
+	now := time.Now()
+	if err := g.checkFlag(now); err != nil {
+		return err
+	}
+	g.end(now, EndReasonResign, playerId != g.Player1Id, playerId == g.Player1Id, false)
+	return nil
+}
+
+// OfferDraw offers the opponent a draw, answering an offer of the opponent accepts it. bots decline right away.
+// a move of the opponent declines the offer
+func (g *Game) OfferDraw(playerId string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.State.End {
+		return GameAlreadyFinishedErr
+	}
+	if playerId == "" || (playerId != g.Player1Id && playerId != g.Player2Id) {
+		return InvalidPlayerIdErr
+	}
+	if g.Player2Id == "" {
+		return NoOpponentErr
+	}
+	if err := g.checkFlag(time.Now()); err != nil {
+		return err
+	}
+	player := 1
+	if playerId == g.Player2Id {
+		player = 2
+	}
+	switch g.State.DrawOfferedBy {
+	case player:
+		return DrawOfferPendingErr
+	case 0:
+	default:
+		g.end(time.Now(), EndReasonAgreement, false, false, true)
+		return nil
+	}
+	if g.Bot != nil {
+		return BotDeclinesDrawErr
+	}
+	g.State.DrawOfferedBy = player
+	g.bump()
+	return nil
+}
+
+// RespondDraw accepts or declines the opponent's draw offer, accepting ends the game in a draw
+func (g *Game) RespondDraw(playerId string, accept bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.State.End {
+		return GameAlreadyFinishedErr
+	}
+	if playerId == "" || (playerId != g.Player1Id && playerId != g.Player2Id) {
+		return InvalidPlayerIdErr
+	}
+	if err := g.checkFlag(time.Now()); err != nil {
+		return err
+	}
+	offerer := g.State.DrawOfferedBy
+	if offerer == 0 {
+		return NoDrawOfferErr
+	}
+	if (offerer == 1 && playerId == g.Player1Id) || (offerer == 2 && playerId == g.Player2Id) {
+		return OwnDrawOfferErr
+	}
+	if !accept {
+		g.State.DrawOfferedBy = 0
+		g.bump()
+		return nil
+	}
+	g.end(time.Now(), EndReasonAgreement, false, false, true)
+	return nil
+}
+
+// EndGame the player abandons the game. the opponent wins if the game started, otherwise it ends without a result
+func (g *Game) EndGame(gameId string, playerId string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if gameId != g.Id {
+		return GameIdNotfoundErr
+	}
+	if playerId == "" || (playerId != g.Player1Id && playerId != g.Player2Id) {
+		return InvalidPlayerIdErr
+	}
+	if g.State.End {
+		return GameAlreadyFinishedErr
+	}
+	now := time.Now()
+	if err := g.checkFlag(now); err != nil {
+		return err
+	}
+	started := g.Player2Id != "" && len(g.Moves) > 0
+	g.end(now, EndReasonAbandon, started && playerId != g.Player1Id, started && playerId == g.Player1Id, false)
+	return nil
+}
+
+// end ends the game off the board at now for the reason, the caller holds the lock
+func (g *Game) end(now time.Time, reason EndReason, player1Won bool, player2Won bool, draw bool) {
+	g.Clocks = g.remaining(now)
+	g.State.End = true
+	g.State.EndTime = now
+	g.State.EndReason = reason
+	g.State.Player1Won = player1Won
+	g.State.Player2Won = player2Won
+	g.State.Draw = draw
+	g.State.DrawOfferedBy = 0
+	g.State.TakebackRequestedBy = 0
+	g.stopClock()
+	g.bump()
+}
@@ -0,0 +1,181 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestOutcomeGame(t *testing.T, moves int) *Game {
+	gf := &NewGameFactory{}
+	g, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	if err := g.Join(g.Id, "test_player2_id", "john"); err != nil {
+		t.Fatalf("Join() unexpected error %v", err)
+	}
+	for i, m := range []Position{{Row: 0, Column: 0}, {Row: 1, Column: 1}}[:moves] {
+		playerId := g.Player1Id
+		if i%2 == 1 {
+			playerId = g.Player2Id
+		}
+		if err := g.Move(playerId, m.Row, m.Column); err != nil {
+			t.Fatalf("Move() unexpected error %v", err)
+		}
+	}
+	return g
+}
+
+func TestGame_Outcome(t *testing.T) {
+	tests := []struct {
+		name string
+		// moves played before the actions, at most 2
+		moves      int
+		actions    func(g *Game) error
+		wantErr    error
+		wantStatus Status
+		wantWinner int
+		wantReason EndReason
+	}{
+		{
+			name:       "player 1 resigns",
+			actions:    func(g *Game) error { return g.Resign(g.Player1Id) },
+			wantStatus: StatusWon,
+			wantWinner: 2,
+			wantReason: EndReasonResign,
+		},
+		{
+			name:       "player 2 resigns",
+			moves:      1,
+			actions:    func(g *Game) error { return g.Resign(g.Player2Id) },
+			wantStatus: StatusWon,
+			wantWinner: 1,
+			wantReason: EndReasonResign,
+		},
+		{
+			name:       "abandon before the first move",
+			actions:    func(g *Game) error { return g.EndGame(g.Id, g.Player2Id) },
+			wantStatus: StatusAbandoned,
+			wantReason: EndReasonAbandon,
+		},
+		{
+			name:       "abandon a started game",
+			moves:      2,
+			actions:    func(g *Game) error { return g.EndGame(g.Id, g.Player2Id) },
+			wantStatus: StatusWon,
+			wantWinner: 1,
+			wantReason: EndReasonAbandon,
+		},
+		{
+			name: "draw offer accepted",
+			actions: func(g *Game) error {
+				if err := g.OfferDraw(g.Player1Id); err != nil {
+					return err
+				}
+				return g.RespondDraw(g.Player2Id, true)
+			},
+			wantStatus: StatusDraw,
+			wantReason: EndReasonAgreement,
+		},
+		{
+			name: "crossing draw offers agree",
+			actions: func(g *Game) error {
+				if err := g.OfferDraw(g.Player2Id); err != nil {
+					return err
+				}
+				return g.OfferDraw(g.Player1Id)
+			},
+			wantStatus: StatusDraw,
+			wantReason: EndReasonAgreement,
+		},
+		{
+			name: "draw offer declined",
+			actions: func(g *Game) error {
+				if err := g.OfferDraw(g.Player1Id); err != nil {
+					return err
+				}
+				return g.RespondDraw(g.Player2Id, false)
+			},
+			wantStatus: StatusInProgress,
+		},
+		{
+			name: "a move declines the draw offer",
+			actions: func(g *Game) error {
+				if err := g.OfferDraw(g.Player1Id); err != nil {
+					return err
+				}
+				if err := g.Move(g.Player1Id, 0, 0); err != nil {
+					return err
+				}
+				if err := g.Move(g.Player2Id, 1, 1); err != nil {
+					return err
+				}
+				return g.RespondDraw(g.Player2Id, true)
+			},
+			wantErr:    NoDrawOfferErr,
+			wantStatus: StatusInProgress,
+		},
+		{
+			name: "own draw offer can not be answered",
+			actions: func(g *Game) error {
+				if err := g.OfferDraw(g.Player1Id); err != nil {
+					return err
+				}
+				return g.RespondDraw(g.Player1Id, true)
+			},
+			wantErr:    OwnDrawOfferErr,
+			wantStatus: StatusInProgress,
+		},
+		{
+			name: "draw offer twice",
+			actions: func(g *Game) error {
+				if err := g.OfferDraw(g.Player1Id); err != nil {
+					return err
+				}
+				return g.OfferDraw(g.Player1Id)
+			},
+			wantErr:    DrawOfferPendingErr,
+			wantStatus: StatusInProgress,
+		},
+		{
+			name: "resign a finished game",
+			actions: func(g *Game) error {
+				if err := g.Resign(g.Player1Id); err != nil {
+					return err
+				}
+				return g.Resign(g.Player2Id)
+			},
+			wantErr:    GameAlreadyFinishedErr,
+			wantStatus: StatusWon,
+			wantWinner: 2,
+			wantReason: EndReasonResign,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestOutcomeGame(t, tt.moves)
+			if err := tt.actions(g); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("actions error = %v, wantErr %v", err, tt.wantErr)
+			}
+			v := g.View()
+			if v.Status != tt.wantStatus || v.Winner != tt.wantWinner || v.Reason != tt.wantReason {
+				t.Errorf("View() status = %q winner = %d reason = %q, want %q %d %q",
+					v.Status, v.Winner, v.Reason, tt.wantStatus, tt.wantWinner, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestGame_OfferDraw_Bot(t *testing.T) {
+	gf := &NewGameFactory{Bot: "random"}
+	g, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	if err := g.OfferDraw(g.Player1Id); !errors.Is(err, BotDeclinesDrawErr) {
+		t.Errorf("OfferDraw() error = %v, want %v", err, BotDeclinesDrawErr)
+	}
+	if g.State.DrawOfferedBy != 0 {
+		t.Errorf("DrawOfferedBy = %d, want no pending offer", g.State.DrawOfferedBy)
+	}
+}
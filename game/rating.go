@@ -0,0 +1,33 @@
+package game
+
+// PlayerRating rating of a player in a rated game
+type PlayerRating struct {
+	// Rating and Deviation of the player when the game started
+	Rating    float64 `json:"rating"`
+	Deviation float64 `json:"deviation"`
+	// Change rating gained or lost once the finished game was rated, 0 before
+	Change float64 `json:"change,omitempty"`
+}
+
+// Rated checks if the game counts for the ratings of the players, that is two different accounts play each other
+func (g *Game) Rated() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rated()
+}
+
+// rated the caller holds the lock
+func (g *Game) rated() bool {
+	return g.Bot == nil && g.Player1AccountId != "" && g.Player2AccountId != "" && g.Player1AccountId != g.Player2AccountId
+}
+
+// SetRating shows the rating of player 1 or 2 in the game
+func (g *Game) SetRating(player int, rating PlayerRating) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if player != 1 && player != 2 {
+		return
+	}
+	g.Ratings[player-1] = &rating
+	g.bump()
+}
@@ -0,0 +1,99 @@
+package game
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// predefined errors
+
+var (
+	GameNotFinishedErr       = errors.New("game is not finished yet. a rematch can be requested once it is over")
+	RematchPendingErr        = errors.New("your rematch request is pending. please wait for the other player")
+	RematchAlreadyCreatedErr = errors.New("the rematch was already created. see rematchGameId in the game state")
+)
+
+// RequestRematch asks for a rematch of the finished game. once both players asked, bots right away, the rematch
+// is created and returned, nil is returned while waiting for the opponent
+func (g *Game) RequestRematch(playerId string) (*Game, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.State.End {
+		return nil, GameNotFinishedErr
+	}
+	if playerId == "" || (playerId != g.Player1Id && playerId != g.Player2Id) {
+		return nil, InvalidPlayerIdErr
+	}
+	if g.Player2Id == "" {
+		return nil, NoOpponentErr
+	}
+	if g.RematchGameId != "" {
+		return nil, RematchAlreadyCreatedErr
+	}
+	player := 1
+	if playerId == g.Player2Id {
+		player = 2
+	}
+	if g.RematchRequestedBy == player {
+		return nil, RematchPendingErr
+	}
+	if g.RematchRequestedBy == 0 && g.Bot == nil {
+		g.RematchRequestedBy = player
+		g.bump()
+		return nil, nil
+	}
+	r := g.rematch()
+	g.RematchRequestedBy = 0
+	g.RematchGameId = r.Id
+	g.bump()
+	return r, nil
+}
+
+// rematch returns a new game between the same players with the same settings and X and O swapped,
+// the caller holds the lock
+func (g *Game) rematch() *Game {
+	// X moves first, so the player who was O starts. in the swap2 opening the other player opens instead
+	state := State{
+		Player2Turn:   !g.initialState.ColorsSwapped,
+		ColorsSwapped: !g.initialState.ColorsSwapped,
+	}
+	if g.initialState.Phase != "" {
+		state = State{
+			Player2Turn: !g.initialState.Player2Turn,
+			Phase:       g.initialState.Phase,
+		}
+	}
+	// the rematch gets a bot of its own, bots such as the random bot are not safe to share between games
+	bot := g.Bot
+	if bot != nil {
+		if b, err := NewBot(bot.Level()); err == nil {
+			bot = b
+		}
+	}
+	r := &Game{
+		Id:               uuid.NewString(),
+		Player1Id:        g.Player1Id,
+		Player1Name:      g.Player1Name,
+		Player2Id:        g.Player2Id,
+		Player2Name:      g.Player2Name,
+		Player1AccountId: g.Player1AccountId,
+		Player2AccountId: g.Player2AccountId,
+		Board:            newBoard(len(g.Board)),
+		WinLength:        g.WinLength,
+		ExactWinLength:   g.ExactWinLength,
+		State:            state,
+		initialState:     state,
+		Bot:              bot,
+		rules:            g.rules,
+		TimeControl:      g.TimeControl,
+		onFlagFall:       g.onFlagFall,
+		PreviousGameId:   g.Id,
+		Private:          g.Private,
+		mu:               &sync.Mutex{},
+	}
+	r.startClock(time.Now())
+	return r
+}
@@ -0,0 +1,91 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGame_RequestRematch(t *testing.T) {
+	g := newTestOutcomeGame(t, 1)
+	if _, err := g.RequestRematch(g.Player1Id); !errors.Is(err, GameNotFinishedErr) {
+		t.Fatalf("RequestRematch() error = %v, want %v", err, GameNotFinishedErr)
+	}
+	if err := g.Resign(g.Player2Id); err != nil {
+		t.Fatalf("Resign() unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		playerId  string
+		wantErr   error
+		wantMatch bool
+	}{
+		{name: "unknown player", playerId: "invalid player id", wantErr: InvalidPlayerIdErr},
+		{name: "player 2 asks first", playerId: g.Player2Id},
+		{name: "player 2 asks again", playerId: g.Player2Id, wantErr: RematchPendingErr},
+		{name: "player 1 accepts", playerId: g.Player1Id, wantMatch: true},
+		{name: "rematch exists", playerId: g.Player2Id, wantErr: RematchAlreadyCreatedErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := g.RequestRematch(tt.playerId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RequestRematch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (r != nil) != tt.wantMatch {
+				t.Fatalf("RequestRematch() = %v, want a rematch %v", r, tt.wantMatch)
+			}
+			if r == nil {
+				return
+			}
+			v := r.View()
+			if v.PreviousGameId != g.Id || g.View().RematchGameId != r.Id {
+				t.Errorf("expect the games to be linked, previous %q rematch %q", v.PreviousGameId, g.RematchGameId)
+			}
+			// player 2 plays X and starts
+			if v.Players[0].Symbol != "O" || v.Players[1].Symbol != "X" || v.Turn != 2 || v.Status != StatusInProgress {
+				t.Errorf("expect X and O swapped with player 2 to move, got %+v", v)
+			}
+			if err := r.Move(r.Player2Id, 1, 1); err != nil || r.Board[1][1] != 1 {
+				t.Errorf("Move() error = %v board %v, want X placed by player 2", err, r.Board)
+			}
+			// a rematch of the rematch swaps back
+			if err := r.Resign(r.Player1Id); err != nil {
+				t.Fatalf("Resign() unexpected error %v", err)
+			}
+			r.RequestRematch(r.Player1Id)
+			rr, _ := r.RequestRematch(r.Player2Id)
+			if v := rr.View(); v.Players[0].Symbol != "X" || v.Turn != 1 {
+				t.Errorf("expect the second rematch to swap back, got %+v", v)
+			}
+		})
+	}
+}
+
+func TestGame_RequestRematch_Swap2(t *testing.T) {
+	gf := &NewGameFactory{Variant: "gomoku", Opening: Swap2Opening, Bot: "random"}
+	g, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	if err := g.Resign(g.Player1Id); err != nil {
+		t.Fatalf("Resign() unexpected error %v", err)
+	}
+	// the bot accepts right away
+	r, err := g.RequestRematch(g.Player1Id)
+	if err != nil || r == nil {
+		t.Fatalf("RequestRematch() = %v, %v, want the rematch", r, err)
+	}
+	if r.Bot == g.Bot || r.Bot.Level() != g.Bot.Level() {
+		t.Errorf("expect a new %s bot in the rematch, got %v", g.Bot.Level(), r.Bot)
+	}
+	if !r.State.Player2Turn || r.State.Phase != PhaseSwap2PlaceThree {
+		t.Errorf("expect the bot to open the swap2 rematch, got %+v", r.State)
+	}
+	if err := r.PlayBotTurn(); err != nil {
+		t.Fatalf("PlayBotTurn() unexpected error %v", err)
+	}
+	if r.stoneCount() != 3 || r.State.Phase != PhaseSwap2Choose || r.State.Player2Turn {
+		t.Errorf("expect player 1 to choose after the bot placed three stones, got %+v", r.State)
+	}
+}
@@ -0,0 +1,217 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultVariant name of the rules used when no variant is chosen
+const DefaultVariant = "classic"
+
+// board size limits
+
+const (
+	DefaultBoardSize = 3
+	MinBoardSize     = 3
+	MaxBoardSize     = 19
+)
+
+// Position a cell on the board
+type Position struct {
+	Row    int `json:"row"`
+	Column int `json:"column"`
+}
+
+// Rules defines how a game variant is played. Game keeps players, turns and state flags
+// and delegates everything about the board to the rules, so new variants can be added
+// by registering a new Rules implementation without touching Game.Move
+type Rules interface {
+	// Name unique variant name used to select the rules when creating a game
+	Name() string
+	// Configure validates the requested board size and win length and fills in the variant defaults for zero values
+	Configure(size int, winLength int) (int, int, error)
+	// LegalMoves returns all positions the player to move can play
+	LegalMoves(g *Game) []Position
+	// ApplyMove validates the move and places the mark (1 for player 1, -1 for player 2) on the board
+	ApplyMove(g *Game, row int, col int, mark int) error
+	// Terminal checks if the move at row, col finished the game and returns the winning mark, 0 for a draw
+	Terminal(g *Game, row int, col int) (bool, int)
+	// WinningLine returns the positions of the line completed by the move at row, col, nil if it completed none
+	WinningLine(g *Game, row int, col int) []Position
+	// Render renders the board as text
+	Render(g *Game) string
+}
+
+// LineLoser optionally implemented by rules where completing a line loses the game
+type LineLoser interface {
+	// CompletedLineLoses checks if the player completing a line loses
+	CompletedLineLoses() bool
+}
+
+var (
+	rulesRegistry   = map[string]Rules{}
+	rulesRegistryMu = &sync.RWMutex{}
+)
+
+func init() {
+	RegisterRules(Classic{})
+}
+
+// RegisterRules makes the rules available to new games under its name, registering a name twice panics
+func RegisterRules(rules Rules) {
+	rulesRegistryMu.Lock()
+	defer rulesRegistryMu.Unlock()
+	if _, found := rulesRegistry[rules.Name()]; found {
+		panic(fmt.Sprintf("game: rules %s registered twice", rules.Name()))
+	}
+	rulesRegistry[rules.Name()] = rules
+}
+
+// LookupRules returns the registered rules for the variant name
+func LookupRules(name string) (Rules, error) {
+	rulesRegistryMu.RLock()
+	defer rulesRegistryMu.RUnlock()
+	rules, found := rulesRegistry[name]
+	if !found {
+		return nil, fmt.Errorf("%w %q. available variants: %s", UnknownVariantErr, name, strings.Join(variants(), ", "))
+	}
+	return rules, nil
+}
+
+// Variants returns the sorted names of all registered variants
+func Variants() []string {
+	rulesRegistryMu.RLock()
+	defer rulesRegistryMu.RUnlock()
+	return variants()
+}
+
+func variants() []string {
+	names := make([]string, 0, len(rulesRegistry))
+	for name := range rulesRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Classic n x n board where the first player to get win length marks in a row wins, 3 x 3 three in a row by default
+type Classic struct{}
+
+func (Classic) Name() string {
+	return "classic"
+}
+
+func (Classic) Configure(size int, winLength int) (int, int, error) {
+	if size == 0 {
+		size = DefaultBoardSize
+	}
+	if size < MinBoardSize || size > MaxBoardSize {
+		return 0, 0, InvalidBoardSizeErr
+	}
+	if winLength == 0 {
+		winLength = size
+	}
+	if winLength < MinBoardSize || winLength > size {
+		return 0, 0, InvalidWinLengthErr
+	}
+	return size, winLength, nil
+}
+
+// LegalMoves every empty position is legal
+func (Classic) LegalMoves(g *Game) []Position {
+	var moves []Position
+	for i, row := range g.Board {
+		for j, mark := range row {
+			if mark == 0 {
+				moves = append(moves, Position{Row: i, Column: j})
+			}
+		}
+	}
+	return moves
+}
+
+func (Classic) ApplyMove(g *Game, row int, col int, mark int) error {
+	n := len(g.Board)
+	// boundary check
+	if row < 0 || col < 0 || row >= n || col >= n {
+		return InvalidMoveErr
+	}
+	// position filled check
+	if g.Board[row][col] != 0 {
+		return MovePositionFilledErr
+	}
+	g.Board[row][col] = mark
+	g.runningSum.add(n, row, col, mark)
+	return nil
+}
+
+// Terminal the mover wins with win length in a row, it is a draw when all positions are filled
+func (Classic) Terminal(g *Game, row int, col int) (bool, int) {
+	if g.isWinningMove(row, col) {
+		return true, g.Board[row][col]
+	}
+	if g.isBoardFull() {
+		return true, 0
+	}
+	return false, 0
+}
+
+// WinningLine the run of marks through row, col that decided the game
+func (Classic) WinningLine(g *Game, row int, col int) []Position {
+	k := g.winLength()
+	directions := [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for _, d := range directions {
+		length := g.lineLength(row, col, d[0], d[1])
+		if length != k && (length < k || g.ExactWinLength) {
+			continue
+		}
+		// walk back to the first mark of the run, then collect the whole run
+		n := len(g.Board)
+		mark := g.Board[row][col]
+		r, c := row, col
+		for r-d[0] >= 0 && c-d[1] >= 0 && r-d[0] < n && c-d[1] < n && g.Board[r-d[0]][c-d[1]] == mark {
+			r, c = r-d[0], c-d[1]
+		}
+		line := make([]Position, 0, length)
+		for i := 0; i < length; i++ {
+			line = append(line, Position{Row: r + d[0]*i, Column: c + d[1]*i})
+		}
+		return line
+	}
+	return nil
+}
+
+func (Classic) Render(g *Game) string {
+	lineBoard := ""
+	separator := strings.TrimPrefix(strings.Repeat("____", len(g.Board)), "_")
+	// m takes value 1, -1, or 0
+	for _, row := range g.Board {
+		lineBoard += "\n" + separator + "\n"
+		for _, col := range row {
+			lineBoard += markSymbol(col) + " | "
+		}
+	}
+	lineBoard += "\n" + separator + "\n"
+	return lineBoard
+}
+
+// markSymbol returns X for player 1, O for player 2 and a space for an empty slot
+func markSymbol(mark int) string {
+	if mark == 1 {
+		return "X"
+	} else if mark == -1 {
+		return "O"
+	}
+	return " "
+}
+
+// newBoard returns an empty n x n board
+func newBoard(n int) [][]int {
+	board := make([][]int, n)
+	for i := range board {
+		board[i] = make([]int, n)
+	}
+	return board
+}
@@ -0,0 +1,98 @@
+package game
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLookupRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		variant     string
+		want        Rules
+		wantErrType error
+	}{
+		{
+			name:    "classic",
+			variant: "classic",
+			want:    Classic{},
+		},
+		{
+			name:        "UnknownVariantErr",
+			variant:     "chess",
+			wantErrType: UnknownVariantErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LookupRules(tt.variant)
+			if !errors.Is(err, tt.wantErrType) {
+				t.Errorf("LookupRules() error = %v, wantErr %v", err, tt.wantErrType)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LookupRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassic_LegalMoves(t *testing.T) {
+	g := &Game{
+		Board: [][]int{
+			{1, -1, 1}, {0, -1, 0}, {1, 1, -1},
+		},
+	}
+	want := []Position{{Row: 1, Column: 0}, {Row: 1, Column: 2}}
+	if got := (Classic{}).LegalMoves(g); !reflect.DeepEqual(got, want) {
+		t.Errorf("LegalMoves() = %v, want %v", got, want)
+	}
+}
+
+func TestClassic_Terminal(t *testing.T) {
+	tests := []struct {
+		name       string
+		board      [][]int
+		row        int
+		col        int
+		wantEnd    bool
+		wantWinner int
+	}{
+		{
+			name:  "game keeps going",
+			board: [][]int{{1, 0, 0}, {0, -1, 0}, {0, 0, 0}},
+			row:   1,
+			col:   1,
+		},
+		{
+			name:       "player 2 column win",
+			board:      [][]int{{1, -1, 1}, {0, -1, 0}, {1, -1, 0}},
+			row:        2,
+			col:        1,
+			wantEnd:    true,
+			wantWinner: -1,
+		},
+		{
+			name:    "draw",
+			board:   [][]int{{1, -1, 1}, {1, -1, -1}, {-1, 1, 1}},
+			row:     2,
+			col:     2,
+			wantEnd: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{Board: tt.board}
+			// rebuild the running sums from the board
+			for i, row := range tt.board {
+				for j, mark := range row {
+					g.runningSum.add(len(tt.board), i, j, mark)
+				}
+			}
+			end, winner := (Classic{}).Terminal(g, tt.row, tt.col)
+			if end != tt.wantEnd || winner != tt.wantWinner {
+				t.Errorf("Terminal() = %v, %v, want %v, %v", end, winner, tt.wantEnd, tt.wantWinner)
+			}
+		})
+	}
+}
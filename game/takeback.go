@@ -0,0 +1,111 @@
+package game
+
+import (
+	"errors"
+	"time"
+)
+
+// predefined errors
+
+var (
+	NoMoveToTakeBackErr   = errors.New("no move of yours to take back")
+	TakebackPendingErr    = errors.New("a takeback request is pending. please accept or decline it first")
+	NoTakebackRequestErr  = errors.New("no takeback request pending")
+	OwnTakebackRequestErr = errors.New("you can not answer your own takeback request. please wait for the other player")
+)
+
+// RequestTakeback asks the opponent to take back the player's last move and any reply to it.
+// bots accept right away
+func (g *Game) RequestTakeback(playerId string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.State.End {
+		return GameAlreadyFinishedErr
+	}
+	if playerId == "" || (playerId != g.Player1Id && playerId != g.Player2Id) {
+		return InvalidPlayerIdErr
+	}
+	if err := g.checkFlag(time.Now()); err != nil {
+		return err
+	}
+	if g.State.TakebackRequestedBy != 0 {
+		return TakebackPendingErr
+	}
+	player := 1
+	if playerId == g.Player2Id {
+		player = 2
+	}
+	if g.lastMoveOf(player) < 0 {
+		return NoMoveToTakeBackErr
+	}
+	if g.Bot != nil {
+		return g.takeBack(player)
+	}
+	g.State.TakebackRequestedBy = player
+	g.bump()
+	return nil
+}
+
+// RespondTakeback accepts or declines the opponent's takeback request, accepting rolls the board back
+func (g *Game) RespondTakeback(playerId string, accept bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.State.End {
+		return GameAlreadyFinishedErr
+	}
+	if playerId == "" || (playerId != g.Player1Id && playerId != g.Player2Id) {
+		return InvalidPlayerIdErr
+	}
+	if err := g.checkFlag(time.Now()); err != nil {
+		return err
+	}
+	requester := g.State.TakebackRequestedBy
+	if requester == 0 {
+		return NoTakebackRequestErr
+	}
+	if (requester == 1 && playerId == g.Player1Id) || (requester == 2 && playerId == g.Player2Id) {
+		return OwnTakebackRequestErr
+	}
+	g.State.TakebackRequestedBy = 0
+	if !accept {
+		g.bump()
+		return nil
+	}
+	return g.takeBack(requester)
+}
+
+// takeBack rolls the game back to before the player's last move by replaying the history up to it,
+// the caller holds the lock
+func (g *Game) takeBack(player int) error {
+	last := g.lastMoveOf(player)
+	if last < 0 {
+		return NoMoveToTakeBackErr
+	}
+	r, err := g.replay(g.Moves[:last])
+	if err != nil {
+		return err
+	}
+	// the time spent so far stays spent, the clock of the player to move after the takeback starts now
+	now := time.Now()
+	g.Clocks = g.remaining(now)
+	g.Board = r.Board
+	g.MetaBoard = r.MetaBoard
+	drawOfferedBy := g.State.DrawOfferedBy
+	g.State = r.State
+	g.State.DrawOfferedBy = drawOfferedBy
+	g.Moves = r.Moves
+	g.runningSum = r.runningSum
+	g.resumeClock(now)
+	g.bump()
+	return nil
+}
+
+// lastMoveOf returns the index of the player's last entry in the history, -1 if the player has not moved
+func (g *Game) lastMoveOf(player int) int {
+	for i := len(g.Moves) - 1; i >= 0; i-- {
+		if g.Moves[i].Player == player {
+			return i
+		}
+	}
+	return -1
+}
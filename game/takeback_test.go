@@ -0,0 +1,145 @@
+package game
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newTestTakebackGame(t *testing.T) *Game {
+	gf := &NewGameFactory{}
+	g, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	if err := g.Join(g.Id, "test_player2_id", "john"); err != nil {
+		t.Fatalf("Join() unexpected error %v", err)
+	}
+	// X at 0,0, O at 1,1, X at 0,1
+	for i, m := range []Position{{Row: 0, Column: 0}, {Row: 1, Column: 1}, {Row: 0, Column: 1}} {
+		playerId := g.Player1Id
+		if i%2 == 1 {
+			playerId = g.Player2Id
+		}
+		if err := g.Move(playerId, m.Row, m.Column); err != nil {
+			t.Fatalf("Move() unexpected error %v", err)
+		}
+	}
+	return g
+}
+
+func TestGame_Takeback(t *testing.T) {
+	tests := []struct {
+		name            string
+		requester       int
+		accept          bool
+		wantBoard       [][]int
+		wantPlayer2Turn bool
+	}{
+		{
+			name:            "player 1 takes back the last move",
+			requester:       1,
+			accept:          true,
+			wantBoard:       [][]int{{1, 0, 0}, {0, -1, 0}, {0, 0, 0}},
+			wantPlayer2Turn: false,
+		},
+		{
+			name:            "player 2 takes back its move and the reply",
+			requester:       2,
+			accept:          true,
+			wantBoard:       [][]int{{1, 0, 0}, {0, 0, 0}, {0, 0, 0}},
+			wantPlayer2Turn: true,
+		},
+		{
+			name:            "declined takeback keeps the board",
+			requester:       1,
+			accept:          false,
+			wantBoard:       [][]int{{1, 1, 0}, {0, -1, 0}, {0, 0, 0}},
+			wantPlayer2Turn: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestTakebackGame(t)
+			requester, responder := g.Player1Id, g.Player2Id
+			if tt.requester == 2 {
+				requester, responder = g.Player2Id, g.Player1Id
+			}
+			if err := g.RequestTakeback(requester); err != nil {
+				t.Fatalf("RequestTakeback() unexpected error %v", err)
+			}
+			if err := g.Move(g.Player2Id, 2, 2); !errors.Is(err, TakebackPendingErr) {
+				t.Errorf("Move() error = %v, wantErr %v", err, TakebackPendingErr)
+			}
+			if err := g.RespondTakeback(requester, true); !errors.Is(err, OwnTakebackRequestErr) {
+				t.Errorf("RespondTakeback() error = %v, wantErr %v", err, OwnTakebackRequestErr)
+			}
+			if err := g.RespondTakeback(responder, tt.accept); err != nil {
+				t.Fatalf("RespondTakeback() unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(g.Board, tt.wantBoard) || g.State.Player2Turn != tt.wantPlayer2Turn || g.State.TakebackRequestedBy != 0 {
+				t.Errorf("after takeback board %v state %+v, want board %v player 2 turn %v", g.Board, g.State, tt.wantBoard, tt.wantPlayer2Turn)
+			}
+			if len(g.Moves) != g.stoneCount() {
+				t.Errorf("history has %d moves for %d stones", len(g.Moves), g.stoneCount())
+			}
+		})
+	}
+}
+
+func TestGame_TakebackRunningSum(t *testing.T) {
+	g := newTestTakebackGame(t)
+	if err := g.RequestTakeback(g.Player1Id); err != nil {
+		t.Fatalf("RequestTakeback() unexpected error %v", err)
+	}
+	if err := g.RespondTakeback(g.Player2Id, true); err != nil {
+		t.Fatalf("RespondTakeback() unexpected error %v", err)
+	}
+	// X completes the first column, the running sums must not count the taken back move at 0,1
+	for _, m := range []struct {
+		playerId string
+		row      int
+		col      int
+	}{{g.Player1Id, 1, 0}, {g.Player2Id, 0, 1}, {g.Player1Id, 2, 0}} {
+		if err := g.Move(m.playerId, m.row, m.col); err != nil {
+			t.Fatalf("Move() unexpected error %v", err)
+		}
+	}
+	if !g.State.Player1Won || g.runningSum.rowSum[0] != 0 {
+		t.Errorf("expect player 1 to win with row sums %v, state %+v", g.runningSum.rowSum, g.State)
+	}
+}
+
+func TestGame_TakebackFailCases(t *testing.T) {
+	g := newTestTakebackGame(t)
+	if err := g.RespondTakeback(g.Player2Id, true); !errors.Is(err, NoTakebackRequestErr) {
+		t.Errorf("RespondTakeback() error = %v, wantErr %v", err, NoTakebackRequestErr)
+	}
+	if err := g.EndGame(g.Id, g.Player1Id); err != nil {
+		t.Fatalf("EndGame() unexpected error %v", err)
+	}
+	if err := g.RequestTakeback(g.Player1Id); !errors.Is(err, GameAlreadyFinishedErr) {
+		t.Errorf("RequestTakeback() error = %v, wantErr %v", err, GameAlreadyFinishedErr)
+	}
+
+	fresh := newTestBotGame(t, &NewGameFactory{Bot: BotMinimax})
+	if err := fresh.RequestTakeback(fresh.Player1Id); !errors.Is(err, NoMoveToTakeBackErr) {
+		t.Errorf("RequestTakeback() error = %v, wantErr %v", err, NoMoveToTakeBackErr)
+	}
+}
+
+func TestGame_TakebackAgainstBot(t *testing.T) {
+	g := newTestBotGame(t, &NewGameFactory{Bot: BotMinimax})
+	if err := g.Move(g.Player1Id, 0, 0); err != nil {
+		t.Fatalf("Move() unexpected error %v", err)
+	}
+	if err := g.PlayBotTurn(); err != nil {
+		t.Fatalf("PlayBotTurn() unexpected error %v", err)
+	}
+	if err := g.RequestTakeback(g.Player1Id); err != nil {
+		t.Fatalf("RequestTakeback() unexpected error %v", err)
+	}
+	if g.stoneCount() != 0 || g.State.Player2Turn || len(g.Moves) != 0 {
+		t.Errorf("expect the bot to accept and roll back both moves, board %v", g.Board)
+	}
+}
@@ -0,0 +1,285 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	RegisterRules(Ultimate{})
+}
+
+// ultimate board dimensions, a 3 x 3 grid of 3 x 3 sub-boards
+const (
+	subBoardSize  = 3
+	ultimateSize  = subBoardSize * subBoardSize
+	subBoardDrawn = 2
+)
+
+// predefined errors
+
+var (
+	SubBoardNotSupportedErr = errors.New("invalid move. sub-board coordinates are only supported by the ultimate variant")
+	InvalidSubBoardMoveErr  = errors.New("invalid move. constraints: 0 <= sub-board row, sub-board column, row, column < 3")
+	SubBoardClosedErr       = errors.New("invalid move. sub-board is already won or full")
+	WrongSubBoardErr        = errors.New("invalid move. you must play in the sub-board sent by your opponent's last move")
+)
+
+// SubBoardRules implemented by variants whose moves can be addressed by sub-board
+type SubBoardRules interface {
+	// GlobalPosition converts a position inside a sub-board to a position on the game board
+	GlobalPosition(subBoard Position, row int, col int) (int, int, error)
+}
+
+// MetaBoard state of the nine sub-boards of an ultimate tic-tac-toe game
+type MetaBoard struct {
+	// Winners 3 x 3 owner of each sub-board, 1 for player 1, -1 for player 2, 2 for a full sub-board without winner and 0 while open
+	Winners [][]int `json:"winners"`
+	// Next sub-board the next move has to be played in, nil if any open sub-board can be chosen
+	Next *Position `json:"next,omitempty"`
+}
+
+// copy returns a deep copy of the meta-board, nil for games without one
+func (m *MetaBoard) copy() *MetaBoard {
+	if m == nil {
+		return nil
+	}
+	c := &MetaBoard{Winners: make([][]int, len(m.Winners))}
+	for i, row := range m.Winners {
+		c.Winners[i] = append([]int(nil), row...)
+	}
+	if m.Next != nil {
+		next := *m.Next
+		c.Next = &next
+	}
+	return c
+}
+
+// Ultimate nine tic-tac-toe sub-boards laid out as a 3 x 3 meta-board. Moves are stored on the 9 x 9 game board,
+// the cell played inside a sub-board sends the opponent to the matching sub-board and winning three sub-boards
+// in a row wins the game
+type Ultimate struct{}
+
+func (Ultimate) Name() string {
+	return "ultimate"
+}
+
+// Configure the board is always 9 x 9 with three in a row on the meta-board
+func (Ultimate) Configure(size int, winLength int) (int, int, error) {
+	if size != 0 && size != ultimateSize {
+		return 0, 0, fmt.Errorf("%w. ultimate is played on a %d x %d board", InvalidBoardSizeErr, ultimateSize, ultimateSize)
+	}
+	if winLength != 0 && winLength != subBoardSize {
+		return 0, 0, fmt.Errorf("%w. ultimate is won with %d sub-boards in a row", InvalidWinLengthErr, subBoardSize)
+	}
+	return ultimateSize, subBoardSize, nil
+}
+
+// LegalMoves empty positions of the sub-board the player was sent to, or of any open sub-board
+func (u Ultimate) LegalMoves(g *Game) []Position {
+	meta := u.meta(g)
+	var moves []Position
+	for i, row := range g.Board {
+		for j, mark := range row {
+			if mark != 0 || meta.Winners[i/subBoardSize][j/subBoardSize] != 0 {
+				continue
+			}
+			if meta.Next != nil && (meta.Next.Row != i/subBoardSize || meta.Next.Column != j/subBoardSize) {
+				continue
+			}
+			moves = append(moves, Position{Row: i, Column: j})
+		}
+	}
+	return moves
+}
+
+func (u Ultimate) ApplyMove(g *Game, row int, col int, mark int) error {
+	// boundary check
+	if row < 0 || col < 0 || row >= ultimateSize || col >= ultimateSize || len(g.Board) != ultimateSize {
+		return InvalidMoveErr
+	}
+	// position filled check
+	if g.Board[row][col] != 0 {
+		return MovePositionFilledErr
+	}
+	meta := u.meta(g)
+	g.MetaBoard = meta
+	sub := Position{Row: row / subBoardSize, Column: col / subBoardSize}
+	if meta.Winners[sub.Row][sub.Column] != 0 {
+		return SubBoardClosedErr
+	}
+	if meta.Next != nil && *meta.Next != sub {
+		return fmt.Errorf("%w. sub-board row %d, sub-board column %d", WrongSubBoardErr, meta.Next.Row, meta.Next.Column)
+	}
+	g.Board[row][col] = mark
+
+	// close the sub-board if the move won or filled it
+	if u.subBoardWon(g, sub, row, col) {
+		meta.Winners[sub.Row][sub.Column] = mark
+	} else if u.subBoardFull(g, sub) {
+		meta.Winners[sub.Row][sub.Column] = subBoardDrawn
+	}
+
+	// the position inside the sub-board sends the opponent to the matching sub-board, unless it is closed
+	next := Position{Row: row % subBoardSize, Column: col % subBoardSize}
+	if meta.Winners[next.Row][next.Column] == 0 {
+		meta.Next = &next
+	} else {
+		meta.Next = nil
+	}
+	return nil
+}
+
+// Terminal three won sub-boards in a row win the game, it is a draw when every sub-board is closed
+func (u Ultimate) Terminal(g *Game, row int, col int) (bool, int) {
+	meta := u.meta(g)
+	mark := g.Board[row][col]
+	sub := Position{Row: row / subBoardSize, Column: col / subBoardSize}
+	if meta.Winners[sub.Row][sub.Column] == mark && metaLineWon(meta.Winners, sub, mark) {
+		return true, mark
+	}
+	for _, winners := range meta.Winners {
+		for _, winner := range winners {
+			if winner == 0 {
+				return false, 0
+			}
+		}
+	}
+	return true, 0
+}
+
+// WinningLine the three sub-boards in a row that won the game, given as positions on the meta-board
+func (u Ultimate) WinningLine(g *Game, row int, col int) []Position {
+	meta := u.meta(g)
+	mark := g.Board[row][col]
+	sub := Position{Row: row / subBoardSize, Column: col / subBoardSize}
+	if mark == 0 || meta.Winners[sub.Row][sub.Column] != mark {
+		return nil
+	}
+	lines := [4][subBoardSize]Position{}
+	for i := 0; i < subBoardSize; i++ {
+		lines[0][i] = Position{Row: sub.Row, Column: i}
+		lines[1][i] = Position{Row: i, Column: sub.Column}
+		lines[2][i] = Position{Row: i, Column: i}
+		lines[3][i] = Position{Row: i, Column: subBoardSize - 1 - i}
+	}
+	for l, line := range lines {
+		// diagonals only count if the sub-board lies on them
+		if (l == 2 && sub.Row != sub.Column) || (l == 3 && sub.Row != subBoardSize-1-sub.Column) {
+			continue
+		}
+		won := true
+		for _, p := range line {
+			won = won && meta.Winners[p.Row][p.Column] == mark
+		}
+		if won {
+			return line[:]
+		}
+	}
+	return nil
+}
+
+// Render renders the 9 x 9 board with sub-boards separated by double lines, followed by the meta-board
+func (u Ultimate) Render(g *Game) string {
+	meta := u.meta(g)
+	separator := strings.Repeat("_", 4*ultimateSize+2*(subBoardSize-1)-1)
+	lineBoard := ""
+	for i, row := range g.Board {
+		if i%subBoardSize == 0 && i != 0 {
+			lineBoard += "\n" + strings.Repeat("=", len(separator))
+		}
+		lineBoard += "\n" + separator + "\n"
+		for j, col := range row {
+			if j%subBoardSize == 0 && j != 0 {
+				lineBoard += "| "
+			}
+			lineBoard += markSymbol(col) + " | "
+		}
+	}
+	lineBoard += "\n" + separator + "\n"
+
+	lineBoard += "\nSub-boards:"
+	for _, winners := range meta.Winners {
+		lineBoard += "\n"
+		for _, winner := range winners {
+			if winner == subBoardDrawn {
+				lineBoard += "-"
+			} else if winner == 0 {
+				lineBoard += "."
+			} else {
+				lineBoard += markSymbol(winner)
+			}
+			lineBoard += " "
+		}
+	}
+	if meta.Next != nil {
+		lineBoard += fmt.Sprintf("\nNext move in sub-board row %d, column %d\n", meta.Next.Row, meta.Next.Column)
+	} else {
+		lineBoard += "\nNext move in any open sub-board\n"
+	}
+	return lineBoard
+}
+
+// MoveInSubBoard makes a move at row, col inside the sub-board for variants played on sub-boards
+func (g *Game) MoveInSubBoard(playerId string, subBoard Position, row int, col int) error {
+	rules, ok := g.Rules().(SubBoardRules)
+	if !ok {
+		return SubBoardNotSupportedErr
+	}
+	row, col, err := rules.GlobalPosition(subBoard, row, col)
+	if err != nil {
+		return err
+	}
+	return g.Move(playerId, row, col)
+}
+
+// GlobalPosition converts a position inside a sub-board to a position on the 9 x 9 board
+func (Ultimate) GlobalPosition(subBoard Position, row int, col int) (int, int, error) {
+	for _, v := range []int{subBoard.Row, subBoard.Column, row, col} {
+		if v < 0 || v >= subBoardSize {
+			return 0, 0, InvalidSubBoardMoveErr
+		}
+	}
+	return subBoard.Row*subBoardSize + row, subBoard.Column*subBoardSize + col, nil
+}
+
+// meta returns the meta-board of the game, an open one before the first move
+func (Ultimate) meta(g *Game) *MetaBoard {
+	if g.MetaBoard == nil {
+		return &MetaBoard{Winners: newBoard(subBoardSize)}
+	}
+	return g.MetaBoard
+}
+
+// subBoardWon checks if the move at row, col completes three in a row inside its sub-board
+func (Ultimate) subBoardWon(g *Game, sub Position, row int, col int) bool {
+	cells := make([][]int, subBoardSize)
+	for i := range cells {
+		cells[i] = g.Board[sub.Row*subBoardSize+i][sub.Column*subBoardSize : (sub.Column+1)*subBoardSize]
+	}
+	return metaLineWon(cells, Position{Row: row % subBoardSize, Column: col % subBoardSize}, g.Board[row][col])
+}
+
+func (Ultimate) subBoardFull(g *Game, sub Position) bool {
+	for i := sub.Row * subBoardSize; i < (sub.Row+1)*subBoardSize; i++ {
+		for j := sub.Column * subBoardSize; j < (sub.Column+1)*subBoardSize; j++ {
+			if g.Board[i][j] == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// metaLineWon checks if the row, column or a diagonal through p of a 3 x 3 grid are all mark
+func metaLineWon(cells [][]int, p Position, mark int) bool {
+	rowWon, colWon, diagonalWon, reverseDiagonalWon := true, true, p.Row == p.Column, p.Row == subBoardSize-1-p.Column
+	for i := 0; i < subBoardSize; i++ {
+		rowWon = rowWon && cells[p.Row][i] == mark
+		colWon = colWon && cells[i][p.Column] == mark
+		diagonalWon = diagonalWon && cells[i][i] == mark
+		reverseDiagonalWon = reverseDiagonalWon && cells[i][subBoardSize-1-i] == mark
+	}
+	return rowWon || colWon || diagonalWon || reverseDiagonalWon
+}
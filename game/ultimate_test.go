@@ -0,0 +1,111 @@
+package game
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newTestUltimateGame(t *testing.T) *Game {
+	gf := &NewGameFactory{Variant: "ultimate"}
+	g, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	if err := g.Join(g.Id, "test_player2_id", "john"); err != nil {
+		t.Fatalf("Join() unexpected error %v", err)
+	}
+	return g
+}
+
+func TestUltimate_Configure(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		winLength   int
+		wantErrType error
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:        "InvalidBoardSizeErr",
+			size:        3,
+			wantErrType: InvalidBoardSizeErr,
+		},
+		{
+			name:        "InvalidWinLengthErr",
+			winLength:   4,
+			wantErrType: InvalidWinLengthErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, winLength, err := (Ultimate{}).Configure(tt.size, tt.winLength)
+			if !errors.Is(err, tt.wantErrType) {
+				t.Errorf("Configure() error = %v, wantErr %v", err, tt.wantErrType)
+			}
+			if err == nil && (size != 9 || winLength != 3) {
+				t.Errorf("Configure() = %d, %d, want 9, 3", size, winLength)
+			}
+		})
+	}
+}
+
+func TestGame_UltimateMove(t *testing.T) {
+	g := newTestUltimateGame(t)
+	// player 1 plays the top right cell of the center sub-board, sending player 2 to the top right sub-board
+	if err := g.MoveInSubBoard(g.Player1Id, Position{Row: 1, Column: 1}, 0, 2); err != nil {
+		t.Fatalf("MoveInSubBoard() unexpected error %v", err)
+	}
+	if g.Board[3][5] != 1 {
+		t.Errorf("expect player 1 mark at row 3 column 5, board %v", g.Board)
+	}
+	if want := (&Position{Row: 0, Column: 2}); !reflect.DeepEqual(g.MetaBoard.Next, want) {
+		t.Errorf("next sub-board = %v, want %v", g.MetaBoard.Next, want)
+	}
+	if got := len(g.LegalMoves()); got != 9 {
+		t.Errorf("LegalMoves() returns %d moves, want 9", got)
+	}
+	// player 2 can not play outside the sub-board
+	if err := g.MoveInSubBoard(g.Player2Id, Position{Row: 1, Column: 1}, 0, 0); !errors.Is(err, WrongSubBoardErr) {
+		t.Errorf("MoveInSubBoard() error = %v, wantErr %v", err, WrongSubBoardErr)
+	}
+	if err := g.MoveInSubBoard(g.Player2Id, Position{Row: 0, Column: 2}, 3, 0); !errors.Is(err, InvalidSubBoardMoveErr) {
+		t.Errorf("MoveInSubBoard() error = %v, wantErr %v", err, InvalidSubBoardMoveErr)
+	}
+	if err := g.MoveInSubBoard(g.Player2Id, Position{Row: 0, Column: 2}, 1, 1); err != nil {
+		t.Errorf("MoveInSubBoard() unexpected error %v", err)
+	}
+}
+
+func TestGame_UltimateWin(t *testing.T) {
+	g := newTestUltimateGame(t)
+	// player 1 owns the top left and center sub-boards and has two in a row in the bottom right sub-board
+	g.MetaBoard = &MetaBoard{
+		Winners: [][]int{{1, 0, 0}, {0, 1, 0}, {0, -1, 0}},
+		Next:    &Position{Row: 2, Column: 2},
+	}
+	g.Board[6][6] = 1
+	g.Board[6][7] = 1
+	if err := g.Move(g.Player1Id, 6, 8); err != nil {
+		t.Fatalf("Move() unexpected error %v", err)
+	}
+	if g.MetaBoard.Winners[2][2] != 1 {
+		t.Errorf("expect player 1 to win the bottom right sub-board, meta-board %v", g.MetaBoard.Winners)
+	}
+	if !g.State.End || !g.State.Player1Won {
+		t.Errorf("expect player 1 to win the game, state %v", g.State)
+	}
+}
+
+func TestGame_MoveInSubBoard_NotSupported(t *testing.T) {
+	gf := &NewGameFactory{}
+	g, err := gf.CreateGame("bob")
+	if err != nil {
+		t.Fatalf("CreateGame() unexpected error %v", err)
+	}
+	if err := g.MoveInSubBoard(g.Player1Id, Position{}, 0, 0); !errors.Is(err, SubBoardNotSupportedErr) {
+		t.Errorf("MoveInSubBoard() error = %v, wantErr %v", err, SubBoardNotSupportedErr)
+	}
+}
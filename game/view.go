@@ -0,0 +1,198 @@
+package game
+
+import (
+	"sync"
+	"time"
+)
+
+// Status where the game stands
+type Status string
+
+const (
+	// StatusWaiting the game waits for a second player
+	StatusWaiting    Status = "waiting"
+	StatusInProgress Status = "in_progress"
+	StatusWon        Status = "won"
+	StatusDraw       Status = "draw"
+	// StatusAbandoned the game was left before it started and ended without a result
+	StatusAbandoned Status = "abandoned"
+)
+
+// PlayerView a player as shown to clients, player ids are kept secret as they authorize moves
+type PlayerView struct {
+	// Player 1 or 2
+	Player int    `json:"player"`
+	Name   string `json:"name"`
+	// Symbol X or O
+	Symbol string `json:"symbol"`
+	Bot    bool   `json:"bot,omitempty"`
+	// AccountId account of the player, empty for guests and bots
+	AccountId string `json:"accountId,omitempty"`
+	// Rating only in rated games
+	Rating *PlayerRating `json:"rating,omitempty"`
+}
+
+// GameView structured state of a game for clients
+type GameView struct {
+	GameId  string `json:"gameId"`
+	Variant string `json:"variant"`
+	// Version increases with every change of the game, spectators coming and going do not count,
+	// see GET /games/{gameId}/wait
+	Version int `json:"version"`
+	// Board "X", "O" or null for an empty position
+	Board   [][]*string  `json:"board"`
+	Players []PlayerView `json:"players"`
+	// Turn player 1 or 2 to move, 0 once the game is over
+	Turn   int    `json:"turn,omitempty"`
+	Status Status `json:"status"`
+	// Winner player 1 or 2, 0 while in progress or for a draw
+	Winner int `json:"winner,omitempty"`
+	// WinningLine positions of the line that decided the game, sub-board positions for the ultimate variant
+	WinningLine []Position `json:"winningLine,omitempty"`
+	// LosingLine positions of the line the loser completed in variants where completing a line loses
+	LosingLine []Position `json:"losingLine,omitempty"`
+	EndTime    *time.Time `json:"endTime,omitempty"`
+	// Phase opening phase, empty once normal play started
+	Phase Phase `json:"phase,omitempty"`
+	// TakebackRequestedBy player 1 or 2 waiting for an answer to a takeback request
+	TakebackRequestedBy int `json:"takebackRequestedBy,omitempty"`
+	// MetaBoard sub-board winners and the sub-board to play next, only for the ultimate variant
+	MetaBoard *MetaBoard `json:"metaBoard,omitempty"`
+	// Clock time control and time left, only for timed games
+	Clock *ClockView `json:"clock,omitempty"`
+	// Reason how the game ended, "board", "resign", "agreement", "abandon" or "timeout"
+	Reason EndReason `json:"reason,omitempty"`
+	// DrawOfferedBy player 1 or 2 waiting for an answer to a draw offer
+	DrawOfferedBy int `json:"drawOfferedBy,omitempty"`
+	// PreviousGameId game this game is a rematch of
+	PreviousGameId string `json:"previousGameId,omitempty"`
+	// RematchRequestedBy player 1 or 2 waiting for the opponent to accept a rematch
+	RematchRequestedBy int `json:"rematchRequestedBy,omitempty"`
+	// RematchGameId rematch of the finished game with X and O swapped, once both players asked for it
+	RematchGameId string `json:"rematchGameId,omitempty"`
+	// Private hidden from spectators and from the open games list
+	Private bool `json:"private,omitempty"`
+	// Rated the result counts for the ratings of the players
+	Rated bool `json:"rated,omitempty"`
+	// Spectators number of clients watching the game
+	Spectators int `json:"spectators"`
+}
+
+// ClockView time control and clocks of a timed game in milliseconds
+type ClockView struct {
+	PerMoveMs   int64 `json:"perMoveMs,omitempty"`
+	InitialMs   int64 `json:"initialMs,omitempty"`
+	IncrementMs int64 `json:"incrementMs,omitempty"`
+	// RemainingMs time left for player 1 and player 2, the clock of the player to move is running
+	RemainingMs [2]int64 `json:"remainingMs"`
+}
+
+// symbols shared by all board views
+var (
+	symbolX = "X"
+	symbolO = "O"
+)
+
+// View returns the structured state of the game
+func (g *Game) View() GameView {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	v := GameView{
+		GameId:              g.Id,
+		Variant:             g.Rules().Name(),
+		Version:             g.Version,
+		Board:               make([][]*string, len(g.Board)),
+		Players:             []PlayerView{{Player: 1, Name: g.Player1Name, Symbol: markSymbol(g.playerMark(false)), AccountId: g.Player1AccountId, Rating: g.Ratings[0]}},
+		Status:              StatusInProgress,
+		Phase:               g.State.Phase,
+		TakebackRequestedBy: g.State.TakebackRequestedBy,
+		MetaBoard:           g.MetaBoard.copy(),
+		Reason:              g.State.EndReason,
+		DrawOfferedBy:       g.State.DrawOfferedBy,
+		PreviousGameId:      g.PreviousGameId,
+		RematchRequestedBy:  g.RematchRequestedBy,
+		RematchGameId:       g.RematchGameId,
+		Private:             g.Private,
+		Rated:               g.rated(),
+		Spectators:          g.spectators,
+	}
+	for i, row := range g.Board {
+		v.Board[i] = make([]*string, len(row))
+		for j, mark := range row {
+			switch mark {
+			case 1:
+				v.Board[i][j] = &symbolX
+			case -1:
+				v.Board[i][j] = &symbolO
+			}
+		}
+	}
+	if g.Player2Id != "" {
+		v.Players = append(v.Players, PlayerView{Player: 2, Name: g.Player2Name, Symbol: markSymbol(g.playerMark(true)), Bot: g.Bot != nil, AccountId: g.Player2AccountId, Rating: g.Ratings[1]})
+	}
+
+	switch {
+	case g.State.Player1Won || g.State.Player2Won:
+		v.Status = StatusWon
+		v.Winner = 1
+		if g.State.Player2Won {
+			v.Winner = 2
+		}
+		if loser, ok := g.Rules().(LineLoser); ok && loser.CompletedLineLoses() {
+			v.LosingLine = g.winningLine()
+		} else {
+			v.WinningLine = g.winningLine()
+		}
+	case g.State.Draw:
+		v.Status = StatusDraw
+	case g.State.End:
+		v.Status = StatusAbandoned
+	case g.Player2Id == "":
+		v.Status = StatusWaiting
+		v.Turn = 1
+	default:
+		v.Turn = 1
+		if g.State.Player2Turn {
+			v.Turn = 2
+		}
+	}
+	if g.State.End {
+		endTime := g.State.EndTime
+		v.EndTime = &endTime
+	}
+	if g.TimeControl != nil {
+		clocks := g.remaining(time.Now())
+		v.Clock = &ClockView{
+			PerMoveMs:   g.TimeControl.PerMove.Milliseconds(),
+			InitialMs:   g.TimeControl.Initial.Milliseconds(),
+			IncrementMs: g.TimeControl.Increment.Milliseconds(),
+			RemainingMs: [2]int64{clocks[0].Milliseconds(), clocks[1].Milliseconds()},
+		}
+	}
+	return v
+}
+
+// winningLine returns the line completed by the last move, nil if the game was not decided on the board
+func (g *Game) winningLine() []Position {
+	for i := len(g.Moves) - 1; i >= 0; i-- {
+		if m := g.Moves[i]; m.Choice == "" {
+			return g.Rules().WinningLine(g, m.Row, m.Column)
+		}
+	}
+	return nil
+}
+
+// Watch counts a spectator until the returned function is called
+func (g *Game) Watch() func() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.spectators++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			g.spectators--
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGame_View(t *testing.T) {
+	type move struct {
+		player2 bool
+		row     int
+		col     int
+	}
+	tests := []struct {
+		name            string
+		factory         *NewGameFactory
+		join            bool
+		moves           []move
+		wantStatus      Status
+		wantTurn        int
+		wantWinner      int
+		wantWinningLine []Position
+		wantLosingLine  []Position
+	}{
+		{
+			name:       "waiting for player 2",
+			factory:    &NewGameFactory{},
+			wantStatus: StatusWaiting,
+			wantTurn:   1,
+		},
+		{
+			name:       "in progress",
+			factory:    &NewGameFactory{},
+			join:       true,
+			moves:      []move{{false, 1, 1}},
+			wantStatus: StatusInProgress,
+			wantTurn:   2,
+		},
+		{
+			name:            "player 1 won with the reverse diagonal",
+			factory:         &NewGameFactory{},
+			join:            true,
+			moves:           []move{{false, 0, 2}, {true, 0, 0}, {false, 2, 0}, {true, 0, 1}, {false, 1, 1}},
+			wantStatus:      StatusWon,
+			wantWinner:      1,
+			wantWinningLine: []Position{{Row: 0, Column: 2}, {Row: 1, Column: 1}, {Row: 2, Column: 0}},
+		},
+		{
+			name:            "four in a row on a larger board",
+			factory:         &NewGameFactory{Size: 6, WinLength: 4},
+			join:            true,
+			moves:           []move{{false, 2, 1}, {true, 0, 0}, {false, 2, 3}, {true, 0, 1}, {false, 2, 4}, {true, 0, 2}, {false, 2, 2}},
+			wantStatus:      StatusWon,
+			wantWinner:      1,
+			wantWinningLine: []Position{{Row: 2, Column: 1}, {Row: 2, Column: 2}, {Row: 2, Column: 3}, {Row: 2, Column: 4}},
+		},
+		{
+			name:           "misere line loses",
+			factory:        &NewGameFactory{Variant: "misere"},
+			join:           true,
+			moves:          []move{{false, 0, 0}, {true, 1, 0}, {false, 0, 1}, {true, 1, 1}, {false, 0, 2}},
+			wantStatus:     StatusWon,
+			wantWinner:     2,
+			wantLosingLine: []Position{{Row: 0, Column: 0}, {Row: 0, Column: 1}, {Row: 0, Column: 2}},
+		},
+		{
+			name:       "draw",
+			factory:    &NewGameFactory{},
+			join:       true,
+			moves:      []move{{false, 0, 0}, {true, 0, 1}, {false, 0, 2}, {true, 1, 1}, {false, 1, 0}, {true, 1, 2}, {false, 2, 1}, {true, 2, 0}, {false, 2, 2}},
+			wantStatus: StatusDraw,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := tt.factory.CreateGame("bob")
+			if err != nil {
+				t.Fatalf("CreateGame() unexpected error %v", err)
+			}
+			if tt.join {
+				if err := g.Join(g.Id, "test_player2_id", "john"); err != nil {
+					t.Fatalf("Join() unexpected error %v", err)
+				}
+			}
+			for _, m := range tt.moves {
+				playerId := g.Player1Id
+				if m.player2 {
+					playerId = g.Player2Id
+				}
+				if err := g.Move(playerId, m.row, m.col); err != nil {
+					t.Fatalf("Move() unexpected error %v", err)
+				}
+			}
+			v := g.View()
+			if v.Status != tt.wantStatus || v.Turn != tt.wantTurn || v.Winner != tt.wantWinner {
+				t.Errorf("View() status %q turn %d winner %d, want %q, %d, %d", v.Status, v.Turn, v.Winner, tt.wantStatus, tt.wantTurn, tt.wantWinner)
+			}
+			if !reflect.DeepEqual(v.WinningLine, tt.wantWinningLine) || !reflect.DeepEqual(v.LosingLine, tt.wantLosingLine) {
+				t.Errorf("View() winning line %v losing line %v, want %v, %v", v.WinningLine, v.LosingLine, tt.wantWinningLine, tt.wantLosingLine)
+			}
+			if (v.EndTime != nil) != g.State.End {
+				t.Errorf("View() end time %v for end %v", v.EndTime, g.State.End)
+			}
+			for _, m := range tt.moves {
+				want := "X"
+				if m.player2 {
+					want = "O"
+				}
+				if got := v.Board[m.row][m.col]; got == nil || *got != want {
+					t.Errorf("View() board at %d, %d = %v, want %s", m.row, m.col, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGame_ViewPlayers(t *testing.T) {
+	g := newTestBotGame(t, &NewGameFactory{Bot: BotRandom})
+	v := g.View()
+	want := []PlayerView{
+		{Player: 1, Name: "bob", Symbol: "X"},
+		{Player: 2, Name: "random bot", Symbol: "O", Bot: true},
+	}
+	if !reflect.DeepEqual(v.Players, want) {
+		t.Errorf("View() players %+v, want %+v", v.Players, want)
+	}
+	if v.Board[0][0] != nil {
+		t.Errorf("View() empty position = %v, want nil", *v.Board[0][0])
+	}
+}
+
+func TestGame_View_MetaBoard(t *testing.T) {
+	g := newTestUltimateGame(t)
+	g.MetaBoard = &MetaBoard{Winners: [][]int{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}}, Next: &Position{Row: 1, Column: 1}}
+	v := g.View()
+	// the view keeps the state it was taken with while the game goes on
+	g.MetaBoard.Winners[1][1] = 1
+	g.MetaBoard.Next.Row = 2
+	if v.MetaBoard.Winners[1][1] != 0 || v.MetaBoard.Next.Row != 1 {
+		t.Errorf("View() meta-board %+v changed with the game", v.MetaBoard)
+	}
+}
+
+func TestUltimate_WinningLine(t *testing.T) {
+	g := newTestUltimateGame(t)
+	g.MetaBoard = &MetaBoard{Winners: [][]int{{1, 0, 0}, {1, -1, 0}, {1, 0, -1}}}
+	g.Board[6][0] = 1
+	want := []Position{{Row: 0, Column: 0}, {Row: 1, Column: 0}, {Row: 2, Column: 0}}
+	if got := (Ultimate{}).WinningLine(g, 6, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("WinningLine() = %v, want %v", got, want)
+	}
+	if got := (Ultimate{}).WinningLine(g, 4, 4); got != nil {
+		t.Errorf("WinningLine() = %v, want nil for an empty position", got)
+	}
+}
+
+func TestGame_Watch(t *testing.T) {
+	g := newTestOutcomeGame(t, 0)
+	version := g.View().Version
+	leave1 := g.Watch()
+	leave2 := g.Watch()
+	if v := g.View(); v.Spectators != 2 || v.Version != version {
+		t.Errorf("View() = %d spectators version %d, want 2 spectators version %d", v.Spectators, v.Version, version)
+	}
+	leave1()
+	leave1()
+	if v := g.View(); v.Spectators != 1 {
+		t.Errorf("View().Spectators = %d, want 1 after one spectator left twice", v.Spectators)
+	}
+	leave2()
+	if v := g.View(); v.Spectators != 0 {
+		t.Errorf("View().Spectators = %d, want 0", v.Spectators)
+	}
+}
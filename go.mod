@@ -3,7 +3,8 @@ module github.com/minozihao/tic-tac-toe-server
 go 1.19
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/gorilla/mux v1.8.1 // indirect
-	github.com/patrickmn/go-cache v2.1.0+incompatible // indirect
+	github.com/google/uuid v1.6.0
+	github.com/gorilla/mux v1.8.1
+	github.com/gorilla/websocket v1.5.0
+	github.com/patrickmn/go-cache v2.1.0+incompatible
 )
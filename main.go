@@ -3,11 +3,34 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/minozihao/tic-tac-toe-server/api"
 )
 
 func main() {
-	s := api.NewServer()
+	var options []api.Option
+	// TOKEN_SIGNING_KEY keeps session tokens valid across restarts, a random key is used if it is not set
+	if key := os.Getenv("TOKEN_SIGNING_KEY"); key != "" {
+		options = append(options, api.WithTokenSigningKey([]byte(key)))
+	}
+	// SESSION_IDLE_TTL how long sessions may stay idle before they are reaped, e.g. 30m
+	if ttl := os.Getenv("SESSION_IDLE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("invalid SESSION_IDLE_TTL: %v", err)
+		}
+		options = append(options, api.WithSessionIdleTTL(d))
+	}
+	// INVITE_TTL how long invite codes of private games are valid, e.g. 15m
+	if ttl := os.Getenv("INVITE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("invalid INVITE_TTL: %v", err)
+		}
+		options = append(options, api.WithInviteTTL(d))
+	}
+	s := api.NewServer(options...)
 	log.Fatal(http.ListenAndServe(":8080", s))
 }